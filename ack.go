@@ -1,12 +1,24 @@
 package socketio
 
 import (
+	"context"
 	"errors"
+	"reflect"
+	"sort"
 	"sync"
+	"sync/atomic"
+
+	"github.com/SavvasMohito/go-socket.io-client/parser"
+	"github.com/SavvasMohito/go-socket.io-client/protocol"
+	"github.com/SavvasMohito/go-socket.io-client/utils"
 )
 
 var (
-	ErrorWaiterNotFound = errors.New("Waiter not found")
+	ErrorWaiterNotFound  = errors.New("Waiter not found")
+	ErrorAckEmpty        = errors.New("ack without arguments")
+	ErrorDisconnected    = errors.New("disconnected before the ack")
+	ErrorAckSent         = errors.New("ack already sent")
+	ErrorAckNotRequested = errors.New("ack not requested")
 )
 
 /*
@@ -14,6 +26,11 @@ var (
 Processes functions that require answers, also known as acknowledge or ack
 */
 type ackProcessor struct {
+	// times a pending ack is sent again after a reconnection
+	retries int
+	// events emitted with EmitReliable
+	queue retryQueue
+
 	counter          int
 	counterLock      sync.Mutex
 	resultWaitersMap sync.Map
@@ -36,7 +53,7 @@ func (a *ackProcessor) getNextId() int {
 Just before the ack function called, the waiter should be added
 to wait and receive response to ack call
 */
-func (a *ackProcessor) addWaiter(id int, w chan interface{}) {
+func (a *ackProcessor) addWaiter(id int, w *AckFuture) {
 	a.resultWaitersMap.Store(id, w)
 }
 
@@ -52,9 +69,304 @@ func (a *ackProcessor) removeWaiter(id int) {
 *
 check if waiter with given ack id is exists, and returns it
 */
-func (a *ackProcessor) getWaiter(id int) (chan interface{}, error) {
+func (a *ackProcessor) getWaiter(id int) (*AckFuture, error) {
 	if waiter, ok := a.resultWaitersMap.Load(id); ok {
-		return waiter.(chan interface{}), nil
+		return waiter.(*AckFuture), nil
 	}
 	return nil, ErrorWaiterNotFound
 }
+
+/*
+*
+The connection is lost, the pending acks fail with ErrorDisconnected
+unless resend is true and they have retries left, they are then sent
+again by resend once the namespace is connected. The events emitted
+with EmitReliable are kept unless resend is false.
+*/
+func (a *ackProcessor) disconnected(resend bool) {
+	a.resultWaitersMap.Range(func(key, value interface{}) bool {
+		f := value.(*AckFuture)
+
+		f.lock.Lock()
+		retry := resend && f.msg != nil && f.attempts < a.retries
+		if retry {
+			f.attempts++
+			f.resend = true
+		}
+		f.lock.Unlock()
+
+		if !retry {
+			f.resolve(nil, nil, ErrorDisconnected)
+		}
+		return true
+	})
+
+	if resend {
+		a.queue.pause()
+	} else {
+		a.queue.fail(ErrorDisconnected)
+	}
+}
+
+/*
+*
+Passes the acks kept on disconnection to send, in the order they were emitted
+*/
+func (a *ackProcessor) resend(send func(msg *protocol.Message)) {
+	var futures []*AckFuture
+	a.resultWaitersMap.Range(func(key, value interface{}) bool {
+		f := value.(*AckFuture)
+
+		f.lock.Lock()
+		if f.resend {
+			f.resend = false
+			futures = append(futures, f)
+		}
+		f.lock.Unlock()
+		return true
+	})
+
+	sort.Slice(futures, func(i, j int) bool {
+		return futures[i].id < futures[j].id
+	})
+
+	for _, f := range futures {
+		utils.Debug("[ack] resend:", f.id)
+		send(f.msg)
+	}
+}
+
+/*
+*
+Result of an ack call, resolved once by the answer of the server
+or by an error, it does not hold a goroutine while pending
+*/
+type AckFuture struct {
+	id  int
+	ack *ackProcessor
+
+	// the emitted event, sent again after a reconnection
+	msg      *protocol.Message
+	attempts int
+	resend   bool
+
+	done   chan struct{}
+	result interface{}
+	// arguments of the answer as decoded by the parser, in every mode
+	values    []interface{}
+	err       error
+	callbacks []func(result interface{}, err error)
+	lock      sync.Mutex
+}
+
+func newAckFuture(msg *protocol.Message, ack *ackProcessor) *AckFuture {
+	return &AckFuture{
+		id:   msg.AckId,
+		ack:  ack,
+		msg:  msg,
+		done: make(chan struct{}),
+	}
+}
+
+/*
+*
+Id of the ack call
+*/
+func (f *AckFuture) Id() int {
+	return f.id
+}
+
+/*
+*
+Closed once the future is resolved
+*/
+func (f *AckFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+/*
+*
+Result of a resolved future, nil and nil while it is pending
+*/
+func (f *AckFuture) Result() (interface{}, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.result, f.err
+}
+
+/*
+*
+Waits for the answer of the server, when ctx is done before it
+the call is abandoned and ctx.Err() is returned
+*/
+func (f *AckFuture) Await(ctx context.Context) (interface{}, error) {
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		f.resolve(nil, nil, ctx.Err())
+	}
+
+	return f.Result()
+}
+
+/*
+*
+Registers a callback called with the result of the future,
+right away when it is already resolved. The answer of the server
+is read on the read loop of the connection, callback must not block.
+*/
+func (f *AckFuture) Then(callback func(result interface{}, err error)) {
+	f.lock.Lock()
+	select {
+	case <-f.done:
+		result, err := f.result, f.err
+		f.lock.Unlock()
+		callback(result, err)
+		return
+	default:
+	}
+
+	f.callbacks = append(f.callbacks, callback)
+	f.lock.Unlock()
+}
+
+/*
+*
+Sets the result of the future, the first result wins
+*/
+func (f *AckFuture) resolve(result interface{}, values []interface{}, err error) bool {
+	f.lock.Lock()
+	select {
+	case <-f.done:
+		f.lock.Unlock()
+		return false
+	default:
+	}
+
+	f.result = result
+	f.values = values
+	f.err = err
+	close(f.done)
+	callbacks := f.callbacks
+	f.callbacks = nil
+	f.lock.Unlock()
+
+	if f.ack != nil {
+		f.ack.removeWaiter(f.id)
+	}
+
+	for _, callback := range callbacks {
+		callback(result, err)
+	}
+
+	return true
+}
+
+/*
+*
+Emits acknowledged events, implemented by *Client and *Channel
+*/
+type AckEmitter interface {
+	EmitWithAck(method string, args ...interface{}) *AckFuture
+}
+
+/*
+*
+Emits an event and decodes the first argument of the answer into T,
+the same way in text, msgpack and binary attachments modes
+*/
+func AckAs[T any](ctx context.Context, ch AckEmitter, method string, args ...interface{}) (T, error) {
+	return AwaitAs[T](ctx, ch.EmitWithAck(method, args...))
+}
+
+/*
+*
+Waits for the future and decodes the first argument of the answer into T
+*/
+func AwaitAs[T any](ctx context.Context, f *AckFuture) (T, error) {
+	var result T
+	if _, err := f.Await(ctx); err != nil {
+		return result, err
+	}
+
+	f.lock.Lock()
+	values := f.values
+	f.lock.Unlock()
+
+	if len(values) == 0 {
+		return result, ErrorAckEmpty
+	}
+
+	err := decodeAs(values[0], &result)
+	return result, err
+}
+
+/*
+*
+Decodes a value of the parser into v, values that already fit,
+ps: []byte attachments, are set as is with their numbers as float64
+*/
+func decodeAs(value interface{}, v interface{}) error {
+	target := reflect.ValueOf(v).Elem()
+	if value != nil {
+		if plain := parser.Float64Numbers(value); reflect.TypeOf(plain).AssignableTo(target.Type()) {
+			target.Set(reflect.ValueOf(plain))
+			return nil
+		}
+	}
+
+	marshal, err := utils.Json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return utils.Json.Unmarshal(marshal, v)
+}
+
+/*
+*
+Answers the ack request of an event, passed as last argument to the handlers
+declaring it, ps: func(c *Channel, v string, ack Ack). It may be called later
+and from another goroutine, or not at all.
+*/
+type Ack func(args ...interface{}) error
+
+/*
+*
+Returns the Ack of the event id of the current session of c,
+it answers once and is dropped when the namespace was disconnected since
+*/
+func (c *Channel) newAck(id int, needAck bool) Ack {
+	if !needAck {
+		return func(args ...interface{}) error {
+			return ErrorAckNotRequested
+		}
+	}
+
+	sid := c.Id()
+	var sent atomic.Bool
+
+	return func(args ...interface{}) error {
+		if !sent.CompareAndSwap(false, true) {
+			return ErrorAckSent
+		}
+		if !c.IsAlive() || c.Id() != sid {
+			utils.Debug("[ack] stale answer dropped:", id)
+			return ErrorDisconnected
+		}
+
+		if args == nil {
+			args = []interface{}{}
+		}
+		r := &protocol.Message{
+			Type:  protocol.ACK,
+			Nsp:   c.namespace,
+			AckId: id,
+			Args:  args,
+		}
+
+		c.queue().control <- protocol.GetMsgPacket(r)
+		return nil
+	}
+}
@@ -0,0 +1,91 @@
+package socketio_test
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+)
+
+func TestAckFuture(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL)
+	connectTestClient(t, c)
+	conn, err := srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 200
+	var called atomic.Int32
+	futures := make([]*socketio.AckFuture, n)
+	for i := range futures {
+		futures[i] = c.EmitWithAck("q", i)
+		futures[i].Then(func(result interface{}, err error) {
+			if err == nil {
+				called.Add(1)
+			}
+		})
+	}
+
+	// answered in any order
+	for i := 0; i < n; i++ {
+		p, err := conn.WaitEvent(testTimeout, "/", "q")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := p.Data.([]interface{})
+		conn.Ack("/", p.Id, data[1])
+	}
+
+	for i, f := range futures {
+		select {
+		case <-f.Done():
+		case <-time.After(testTimeout):
+			t.Fatalf("future %d not resolved", i)
+		}
+
+		result, err := f.Await(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if args := result.([]interface{}); len(args) != 1 || string(args[0].([]byte)) != strconv.Itoa(i) {
+			t.Fatalf("future %d: got %v", i, result)
+		}
+	}
+	if called.Load() != n {
+		t.Fatalf("got %d callbacks, want %d", called.Load(), n)
+	}
+}
+
+func TestAckFutureAbandoned(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL)
+	connectTestClient(t, c)
+
+	f := c.EmitWithAck("never")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := f.Await(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("got %v", err)
+	}
+
+	// the callbacks registered late are called right away
+	var late error
+	f.Then(func(result interface{}, err error) { late = err })
+	if late != context.DeadlineExceeded {
+		t.Fatalf("got %v", late)
+	}
+
+	if _, err := c.Ack("never", 20*time.Millisecond); err != socketio.ErrorSendTimeout {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package socketio_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+)
+
+func TestAckAs(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL)
+	connectTestClient(t, c)
+	conn, err := srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	go func() {
+		for _, answer := range []struct {
+			event string
+			args  []interface{}
+		}{
+			{"user", []interface{}{map[string]interface{}{"name": "ann", "age": 3}}},
+			// sent as a BINARY_ACK attachment
+			{"file", []interface{}{[]byte{0, 1, 2}}},
+			{"n", []interface{}{42}},
+			{"empty", nil},
+		} {
+			p, err := conn.WaitEvent(testTimeout, "/", answer.event)
+			if err != nil {
+				return
+			}
+			conn.Ack("/", p.Id, answer.args...)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	u, err := socketio.AckAs[user](ctx, c, "user")
+	if err != nil || u.Name != "ann" || u.Age != 3 {
+		t.Fatal(u, err)
+	}
+	f, err := socketio.AckAs[[]byte](ctx, c, "file")
+	if err != nil || !bytes.Equal(f, []byte{0, 1, 2}) {
+		t.Fatal(f, err)
+	}
+	n, err := socketio.AckAs[int](ctx, c, "n")
+	if err != nil || n != 42 {
+		t.Fatal(n, err)
+	}
+	if _, err := socketio.AckAs[int](ctx, c, "empty"); err != socketio.ErrorAckEmpty {
+		t.Fatalf("got %v, want ErrorAckEmpty", err)
+	}
+}
@@ -0,0 +1,87 @@
+package socketio_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+)
+
+func TestAckDisconnected(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL)
+	connectTestClient(t, c)
+	conn, err := srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := c.EmitWithAck("q")
+	if _, err := conn.WaitEvent(testTimeout, "/", "q"); err != nil {
+		t.Fatal(err)
+	}
+	conn.Drop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	if _, err := f.Await(ctx); err != socketio.ErrorDisconnected {
+		t.Fatalf("got %v, want ErrorDisconnected", err)
+	}
+}
+
+func TestAckResend(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	b := &socketio.ClientBuilder{}
+	c := newTestClient(t, srv.URL, b.WithReconnection(true), b.WithReconnectionDelay(10*time.Millisecond),
+		b.WithAckReconnectRetries(1))
+	connectTestClient(t, c)
+	conn, err := srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f1 := c.EmitWithAck("q", 1)
+	f2 := c.EmitWithAck("q", 2)
+	for i := 0; i < 2; i++ {
+		if _, err := conn.WaitEvent(testTimeout, "/", "q"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn.Drop()
+
+	// sent again in order with the same ids
+	conn, err = srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1, err := conn.WaitEvent(testTimeout, "/", "q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := conn.WaitEvent(testTimeout, "/", "q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p1.Id != f1.Id() || p2.Id != f2.Id() {
+		t.Fatalf("got ids %d %d, want %d %d", p1.Id, p2.Id, f1.Id(), f2.Id())
+	}
+
+	conn.Ack("/", p1.Id, "ok")
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	if _, err := f1.Await(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// no retry left
+	conn.Drop()
+	if _, err := f2.Await(ctx); err != socketio.ErrorDisconnected {
+		t.Fatalf("got %v, want ErrorDisconnected", err)
+	}
+}
@@ -0,0 +1,130 @@
+package socketio
+
+import (
+	"sync"
+	"unsafe"
+)
+
+/*
+*
+Catch-all listener, called with the name and the arguments of every event
+*/
+type AnyHandler func(c *Channel, event string, args ...interface{})
+
+/*
+*
+Catch-all listeners of incoming or outgoing events, in call order
+*/
+type anyListeners struct {
+	handlers []AnyHandler
+	lock     sync.RWMutex
+}
+
+func (l *anyListeners) add(f AnyHandler) {
+	l.lock.Lock()
+	l.handlers = append(l.handlers, f)
+	l.lock.Unlock()
+}
+
+func (l *anyListeners) prepend(f AnyHandler) {
+	l.lock.Lock()
+	l.handlers = append([]AnyHandler{f}, l.handlers...)
+	l.lock.Unlock()
+}
+
+/*
+*
+Removes f, every listener when f is nil
+*/
+func (l *anyListeners) remove(f AnyHandler) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if f == nil {
+		l.handlers = nil
+		return
+	}
+
+	id := funcId(f)
+	for i, h := range l.handlers {
+		if funcId(h) == id {
+			l.handlers = append(l.handlers[:i:i], l.handlers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (l *anyListeners) call(c *Channel, event string, args []interface{}) {
+	l.lock.RLock()
+	handlers := l.handlers
+	l.lock.RUnlock()
+
+	for _, f := range handlers {
+		f(c, event, args...)
+	}
+}
+
+/*
+*
+Adds a listener called for every incoming event, whether it has a handler or not
+*/
+func (c *Client) OnAny(f AnyHandler) {
+	c.handlers.anyIncoming.add(f)
+}
+
+/*
+*
+Adds a listener called for every incoming event before the other catch-all listeners
+*/
+func (c *Client) PrependAny(f AnyHandler) {
+	c.handlers.anyIncoming.prepend(f)
+}
+
+/*
+*
+Removes a listener added with OnAny or PrependAny, all of them when f is nil,
+f must be the very func value that was added
+*/
+func (c *Client) OffAny(f AnyHandler) {
+	c.handlers.anyIncoming.remove(f)
+}
+
+/*
+*
+Adds a listener called for every event emitted
+*/
+func (c *Client) OnAnyOutgoing(f AnyHandler) {
+	c.channel.anyOutgoing.add(f)
+}
+
+/*
+*
+Adds a listener called for every event emitted before the other outgoing listeners
+*/
+func (c *Client) PrependAnyOutgoing(f AnyHandler) {
+	c.channel.anyOutgoing.prepend(f)
+}
+
+/*
+*
+Removes a listener added with OnAnyOutgoing or PrependAnyOutgoing,
+all of them when f is nil
+*/
+func (c *Client) OffAnyOutgoing(f AnyHandler) {
+	c.channel.anyOutgoing.remove(f)
+}
+
+/*
+*
+Identity of a func value, two closures of the same function literal differ,
+while a method value, ps: obj.Method, is a new func value every time it is taken
+*/
+func funcId(f interface{}) uintptr {
+	type eface struct {
+		typ  unsafe.Pointer
+		data unsafe.Pointer
+	}
+
+	// the data of a func in an interface is the pointer to its closure
+	return uintptr((*eface)(unsafe.Pointer(&f)).data)
+}
@@ -0,0 +1,70 @@
+package socketio_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+)
+
+func TestAny(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL)
+
+	var lock sync.Mutex
+	var got []string
+	calls := make(chan struct{}, 8)
+	rec := func(tag string) socketio.AnyHandler {
+		return func(ch *socketio.Channel, event string, args ...interface{}) {
+			lock.Lock()
+			got = append(got, tag+":"+event)
+			lock.Unlock()
+			calls <- struct{}{}
+		}
+	}
+	wait := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			waitSignal(t, calls, "catch-all call")
+		}
+	}
+
+	second := rec("b")
+	c.OnAny(rec("a"))
+	c.OnAny(second)
+	c.PrependAny(rec("first"))
+	c.OnAnyOutgoing(rec("out"))
+
+	connectTestClient(t, c)
+	conn, err := srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// events without a listener are seen too
+	conn.Emit("/", "unknown", 1)
+	wait(3)
+	c.OffAny(second)
+	conn.Emit("/", "again")
+	wait(2)
+	c.Emit("up", 1)
+	wait(1)
+
+	select {
+	case <-calls:
+		t.Fatal("removed handler called")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	want := []string{"first:unknown", "a:unknown", "b:unknown", "first:again", "a:again", "out:up"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
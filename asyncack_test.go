@@ -0,0 +1,90 @@
+package socketio_test
+
+import (
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+)
+
+func TestAsyncAck(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	b := &socketio.ClientBuilder{}
+	c := newTestClient(t, srv.URL, b.WithReconnection(true), b.WithReconnectionDelay(10*time.Millisecond))
+
+	errs := make(chan error, 4)
+	acks := make(chan socketio.Ack, 1)
+	c.On("later", func(ch *socketio.Channel, v string, ack socketio.Ack) string {
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			errs <- ack("done " + v)
+			errs <- ack("twice")
+		}()
+		return "ignored"
+	})
+	c.On("keep", func(ch *socketio.Channel, ack socketio.Ack) { acks <- ack })
+	socketio.On(c, "typed", func(ctx socketio.Context, v int) error {
+		return ctx.Ack(v * 2)
+	})
+	// answered automatically
+	socketio.On(c, "silent", func(ctx socketio.Context, v int) error { return nil })
+	socketio.OnAck(c, "typed later", func(ctx socketio.Context, v int) error {
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			errs <- ctx.Ack(v + 1)
+		}()
+		return nil
+	})
+
+	connectTestClient(t, c)
+	conn, err := srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := conn.EmitWithAck("/", testTimeout, "later", "x")
+	if err != nil || len(res) != 1 || res[0] != "done x" {
+		t.Fatal(res, err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != socketio.ErrorAckSent {
+		t.Fatalf("got %v, want ErrorAckSent", err)
+	}
+
+	res, err = conn.EmitWithAck("/", testTimeout, "typed", 21)
+	if err != nil || len(res) != 1 || res[0] != float64(42) {
+		t.Fatal(res, err)
+	}
+	res, err = conn.EmitWithAck("/", testTimeout, "silent", 1)
+	if err != nil || len(res) != 0 {
+		t.Fatal(res, err)
+	}
+	res, err = conn.EmitWithAck("/", testTimeout, "typed later", 1)
+	if err != nil || len(res) != 1 || res[0] != float64(2) {
+		t.Fatal(res, err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	// a stale answer is not sent on the next connection
+	go conn.EmitWithAck("/", testTimeout, "keep")
+	var ack socketio.Ack
+	select {
+	case ack = <-acks:
+	case <-time.After(testTimeout):
+		t.Fatal("no keep event")
+	}
+	conn.Drop()
+	if _, err := srv.Conn(testTimeout); err != nil {
+		t.Fatal(err)
+	}
+	if err := ack(); err != socketio.ErrorDisconnected {
+		t.Fatalf("got %v, want ErrorDisconnected", err)
+	}
+}
@@ -0,0 +1,111 @@
+package socketio
+
+import (
+	"strings"
+
+	"github.com/SavvasMohito/go-socket.io-client/utils"
+)
+
+/*
+*
+Payload of a CONNECT_ERROR, the server refused the namespace,
+passed to the handlers of OnConnectError
+
+ps: {"message":"not authorized","data":{"code":401}}
+*/
+type ConnectError struct {
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *ConnectError) Error() string {
+	return e.Message
+}
+
+/*
+*
+Parses the payload of a CONNECT_ERROR, in protocol v3 it is a plain string
+*/
+func parseConnectError(data interface{}) *ConnectError {
+	connectErr := &ConnectError{}
+
+	switch v := data.(type) {
+	case string:
+		connectErr.Message = v
+	case nil:
+	default:
+		marshal, err := utils.Json.Marshal(v)
+		if err == nil {
+			utils.Json.Unmarshal(marshal, connectErr)
+		}
+	}
+
+	return connectErr
+}
+
+/*
+*
+Default IsAuthError, looks for the usual words of authentication middlewares
+*/
+func isAuthError(err *ConnectError) bool {
+	msg := strings.ToLower(err.Message)
+	for _, word := range []string{"auth", "token", "credential", "jwt", "expired"} {
+		if strings.Contains(msg, word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+*
+Auth payload of the namespace CONNECT, with the session to recover if any
+*/
+func (c *Client) connectAuth() (interface{}, error) {
+	var auth interface{}
+	if c.authFunc != nil {
+		v, err := c.authFunc()
+		if err != nil {
+			return nil, err
+		}
+		auth = v
+	} else if c.auth != nil {
+		auth = c.auth
+	}
+
+	pid, offset := c.channel.recoveryState()
+	if pid == "" {
+		return auth, nil
+	}
+
+	// the session is added to the fields of the auth object
+	fields := make(map[string]interface{})
+	if auth != nil {
+		marshal, err := utils.Json.Marshal(auth)
+		if err == nil {
+			utils.Json.Unmarshal(marshal, &fields)
+		}
+	}
+	fields["pid"] = pid
+	fields["offset"] = offset
+
+	return fields, nil
+}
+
+/*
+*
+Asked on CONNECT_ERROR, refreshes the credentials and sends the CONNECT
+once more when the server reports an authentication failure,
+otherwise ConnectContext returns err
+*/
+func (c *Client) onConnectError(ch *Channel, err *ConnectError) bool {
+	if c.isAuthError(err) && c.authRetried.CompareAndSwap(false, true) {
+		utils.Debug("[auth] retry connect:", err.Message)
+		c.sendConnect()
+		return true
+	}
+
+	c.connectWaiters.notify(err)
+	return false
+}
@@ -0,0 +1,96 @@
+package socketio_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/parser"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+)
+
+func TestAuthFunc(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	var headers, auths int32
+	b := &socketio.ClientBuilder{}
+	c := newTestClient(t, srv.URL,
+		b.WithReconnection(true), b.WithReconnectionDelay(10*time.Millisecond),
+		b.WithHeaderFunc(func() (http.Header, error) {
+			n := atomic.AddInt32(&headers, 1)
+			return http.Header{"Authorization": {"Bearer " + strconv.Itoa(int(n))}}, nil
+		}),
+		b.WithAuthFunc(func() (interface{}, error) {
+			return map[string]interface{}{"token": atomic.AddInt32(&auths, 1)}, nil
+		}))
+	connectTestClient(t, c)
+
+	// the credentials are asked again on every connection
+	for i := 1; i <= 2; i++ {
+		conn, err := srv.Conn(testTimeout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h := conn.Request().Header.Get("Authorization"); h != "Bearer "+strconv.Itoa(i) {
+			t.Fatalf("connection %d: got header %q", i, h)
+		}
+		connect, err := conn.WaitConnect(testTimeout, "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if auth, _ := connect.Data.(map[string]interface{}); auth["token"] != float64(i) {
+			t.Fatalf("connection %d: got auth %v", i, connect.Data)
+		}
+		conn.Drop()
+	}
+}
+
+func TestAuthRetry(t *testing.T) {
+	for _, tc := range []struct {
+		message  string
+		connects int
+	}{
+		{"jwt expired", 2},
+		// not an authentication failure, no retry
+		{"namespace closed", 1},
+	} {
+		t.Run(tc.message, func(t *testing.T) {
+			srv := sockettest.NewServer()
+			defer srv.Close()
+			srv.Reject("/", tc.message, nil)
+
+			var auths int32
+			b := &socketio.ClientBuilder{}
+			c := newTestClient(t, srv.URL, b.WithAuthFunc(func() (interface{}, error) {
+				return map[string]interface{}{"token": atomic.AddInt32(&auths, 1)}, nil
+			}))
+
+			ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+			defer cancel()
+			var connectErr *socketio.ConnectError
+			if err := c.ConnectContext(ctx); !errors.As(err, &connectErr) || connectErr.Message != tc.message {
+				t.Fatalf("got %v", err)
+			}
+
+			conn, err := srv.Conn(testTimeout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			connects := 0
+			for _, p := range conn.Packets() {
+				if p.Type == parser.CONNECT {
+					connects++
+				}
+			}
+			if connects != tc.connects || atomic.LoadInt32(&auths) != int32(tc.connects) {
+				t.Fatalf("got %d CONNECT, %d auth calls, want %d", connects, auths, tc.connects)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package socketio
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultReconnectionDelay    = 1 * time.Second
+	defaultReconnectionDelayMax = 5 * time.Second
+	defaultRandomizationFactor  = 0.5
+
+	backoffFactor = 2
+)
+
+/*
+*
+Exponential backoff with jitter between reconnection attempts,
+works the same way as the backo2 package of the js client
+*/
+type backoff struct {
+	min      time.Duration
+	max      time.Duration
+	jitter   float64
+	attempts int
+}
+
+/*
+*
+Returns the delay before the next attempt and counts the attempt
+*/
+func (b *backoff) duration() time.Duration {
+	d := float64(b.min) * math.Pow(backoffFactor, float64(b.attempts))
+	b.attempts++
+
+	// past max the delay overflows to +Inf after enough attempts
+	if d > float64(b.max) {
+		d = float64(b.max)
+	}
+
+	if b.jitter > 0 {
+		r := rand.Float64()
+		deviation := math.Floor(r * b.jitter * d)
+		if int(math.Floor(r*10))&1 == 0 {
+			d -= deviation
+		} else {
+			d += deviation
+		}
+	}
+
+	if d > float64(b.max) {
+		return b.max
+	}
+	return time.Duration(d)
+}
+
+func (b *backoff) reset() {
+	b.attempts = 0
+}
@@ -0,0 +1,24 @@
+package socketio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDuration(t *testing.T) {
+	b := backoff{min: 100 * time.Millisecond, max: time.Second, jitter: 0.5}
+
+	for i := 0; i < 5000; i++ {
+		d := b.duration()
+		if d <= 0 || d > b.max {
+			t.Fatalf("attempt %d: got %v", i, d)
+		}
+	}
+
+	b = backoff{min: 100 * time.Millisecond, max: time.Second}
+	for i, want := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		if d := b.duration(); d != want*time.Millisecond {
+			t.Fatalf("attempt %d: got %v, want %v", i, d, want*time.Millisecond)
+		}
+	}
+}
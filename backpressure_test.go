@@ -0,0 +1,155 @@
+package socketio_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/pipe"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+)
+
+/*
+*
+Connected client with a small outgoing queue, the pipe holds a single
+message so that the events stay queued until the server reads them
+*/
+func newBackpressureClient(t *testing.T) (*socketio.Client, *pipe.Connection) {
+	t.Helper()
+
+	d := newPipeDialer()
+	d.transport.BufferSize = 1
+	b := &socketio.ClientBuilder{}
+	c := newTestClient(t, "http://localhost", d.option(),
+		b.WithDataQueueSize(10), b.WithHighWatermark(8), b.WithLowWatermark(2))
+
+	connected := make(chan struct{}, 1)
+	c.On(socketio.OnConnection, func(ch *socketio.Channel) { connected <- struct{}{} })
+	c.Connect()
+	srv := d.accept(t)
+	waitSignal(t, connected, "connection")
+
+	return c, srv
+}
+
+/*
+*
+Queues events with TryEmit until the queue is full, returns how many were queued
+*/
+func fillQueue(t *testing.T, c *socketio.Client) int {
+	t.Helper()
+
+	for n := 0; n < 100; n++ {
+		if err := c.TryEmit("e", n); err != nil {
+			if err != socketio.ErrorSocketOverflood {
+				t.Fatal(err)
+			}
+			return n
+		}
+	}
+	t.Fatal("the queue is never full")
+	return 0
+}
+
+func TestBackpressure(t *testing.T) {
+	c, srv := newBackpressureClient(t)
+
+	high := make(chan struct{}, 1)
+	low := make(chan struct{}, 1)
+	c.On(socketio.OnHighWatermark, func(ch *socketio.Channel) { high <- struct{}{} })
+	c.On(socketio.OnLowWatermark, func(ch *socketio.Channel) { low <- struct{}{} })
+	closed := make(chan struct{}, 1)
+	c.On(socketio.OnDisconnection, func(ch *socketio.Channel) { closed <- struct{}{} })
+
+	n := fillQueue(t, c)
+	waitSignal(t, high, "high watermark")
+	// the write loop took the first event meanwhile
+	time.Sleep(20 * time.Millisecond)
+	n += fillQueue(t, c)
+	// Emit does not wait either
+	if err := c.Emit("e", -1); err != socketio.ErrorSocketOverflood {
+		t.Fatalf("got %v from Emit, want ErrorSocketOverflood", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := c.EmitContext(ctx, "e", -1); err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+
+	// waits for room while the server reads the queued events
+	done := make(chan error, 1)
+	go func() { done <- c.EmitContext(context.Background(), "e", n) }()
+	for i := 0; i <= n; i++ {
+		readMessage(t, srv)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	waitSignal(t, low, "low watermark")
+
+	select {
+	case <-closed:
+		t.Fatal("closed on overflow")
+	default:
+	}
+}
+
+func TestWatermarkOrder(t *testing.T) {
+	c, srv := newBackpressureClient(t)
+
+	var lock sync.Mutex
+	var marks []string
+	got := make(chan struct{}, 8)
+	mark := func(event string) {
+		lock.Lock()
+		marks = append(marks, event)
+		lock.Unlock()
+		got <- struct{}{}
+	}
+	c.On(socketio.OnHighWatermark, func(ch *socketio.Channel) {
+		// the low watermark is reached meanwhile
+		time.Sleep(50 * time.Millisecond)
+		mark(socketio.OnHighWatermark)
+	})
+	c.On(socketio.OnLowWatermark, func(ch *socketio.Channel) {
+		mark(socketio.OnLowWatermark)
+	})
+
+	const cycles = 3
+	for i := 0; i < cycles; i++ {
+		n := fillQueue(t, c)
+		for j := 0; j < n; j++ {
+			readMessage(t, srv)
+		}
+		waitSignal(t, got, "high watermark")
+		waitSignal(t, got, "low watermark")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	for i, event := range marks {
+		want := socketio.OnHighWatermark
+		if i%2 == 1 {
+			want = socketio.OnLowWatermark
+		}
+		if event != want {
+			t.Fatalf("got %v", marks)
+		}
+	}
+}
+
+func TestEmitNotConnected(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL)
+	if err := c.TryEmit("a"); err != socketio.ErrorNotConnected {
+		t.Fatalf("got %v from TryEmit, want ErrorNotConnected", err)
+	}
+	if err := c.EmitContext(context.Background(), "a"); err != socketio.ErrorNotConnected {
+		t.Fatalf("got %v from EmitContext, want ErrorNotConnected", err)
+	}
+}
@@ -0,0 +1,198 @@
+package socketio
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SavvasMohito/go-socket.io-client/protocol"
+	"github.com/SavvasMohito/go-socket.io-client/utils"
+)
+
+/*
+*
+What to do with a packet when the send buffer is full
+*/
+type OverflowPolicy int
+
+const (
+	// DropOldest removes the oldest buffered packet to make room
+	DropOldest OverflowPolicy = iota
+	// DropNewest discards the packet being sent
+	DropNewest
+	// OverflowError discards the packet being sent and returns ErrorSendBufferFull
+	OverflowError
+)
+
+var (
+	ErrorSendBufferFull    = errors.New("send buffer full")
+	ErrorSendBufferExpired = errors.New("send buffer packet expired")
+)
+
+/*
+*
+Passed to the handlers of OnBufferDrop, a buffered packet is lost
+because the buffer is full or it was held longer than SendBufferTTL
+*/
+type DropError struct {
+	// event of the packet
+	Event string
+	// ErrorSendBufferFull or ErrorSendBufferExpired
+	Err error
+}
+
+func (e *DropError) Error() string {
+	return fmt.Sprintf("socket.io: buffered event %q dropped: %v", e.Event, e.Err)
+}
+
+func (e *DropError) Unwrap() error {
+	return e.Err
+}
+
+type bufferedPacket struct {
+	msg     *protocol.Message
+	out     interface{}
+	expires time.Time
+}
+
+/*
+*
+Holds outgoing packets while the namespace is not connected,
+and flushes them in order once it is
+*/
+type sendBuffer struct {
+	size     int
+	ttl      time.Duration
+	overflow OverflowPolicy
+
+	// called without the lock for each packet lost after it was buffered
+	onDrop func(msg *protocol.Message, err error)
+
+	online  bool
+	packets []bufferedPacket
+	lock    sync.Mutex
+}
+
+/*
+*
+Buffers out, the packet of msg, unless the namespace is connected or
+the buffer is disabled, returns true when it was buffered and must not be sent now
+*/
+func (b *sendBuffer) push(msg *protocol.Message, out interface{}) (bool, error) {
+	b.lock.Lock()
+
+	if b.size <= 0 || b.online {
+		b.lock.Unlock()
+		return false, nil
+	}
+
+	now := time.Now()
+	dropped := b.dropExpired(now)
+
+	var full *protocol.Message
+	if len(b.packets) >= b.size {
+		switch b.overflow {
+		case DropNewest:
+			utils.Debug("[buffer] full, dropped newest packet")
+			b.lock.Unlock()
+			b.dropped(dropped, ErrorSendBufferExpired)
+			b.drop(msg, ErrorSendBufferFull)
+			return true, nil
+		case OverflowError:
+			b.lock.Unlock()
+			b.dropped(dropped, ErrorSendBufferExpired)
+			return true, ErrorSendBufferFull
+		default:
+			utils.Debug("[buffer] full, dropped oldest packet")
+			full = b.packets[0].msg
+			b.packets = b.packets[1:]
+		}
+	}
+
+	p := bufferedPacket{msg: msg, out: out}
+	if b.ttl > 0 {
+		p.expires = now.Add(b.ttl)
+	}
+	b.packets = append(b.packets, p)
+	b.lock.Unlock()
+
+	b.dropped(dropped, ErrorSendBufferExpired)
+	if full != nil {
+		b.drop(full, ErrorSendBufferFull)
+	}
+
+	return true, nil
+}
+
+/*
+*
+Passes the buffered packets to flush in order then marks the namespace
+as connected, the packets buffered meanwhile are flushed after them.
+flush returns false when the connection is closed, the packets left
+are then kept for the next connection.
+*/
+func (b *sendBuffer) setOnline(flush func(out interface{}) bool) {
+	for {
+		b.lock.Lock()
+		dropped := b.dropExpired(time.Now())
+		packets := b.packets
+		b.packets = nil
+		if len(packets) == 0 {
+			b.online = true
+		}
+		b.lock.Unlock()
+
+		b.dropped(dropped, ErrorSendBufferExpired)
+		if len(packets) == 0 {
+			return
+		}
+
+		for i, p := range packets {
+			if !flush(p.out) {
+				b.lock.Lock()
+				b.packets = append(packets[i:], b.packets...)
+				b.lock.Unlock()
+				return
+			}
+		}
+	}
+}
+
+func (b *sendBuffer) setOffline() {
+	b.lock.Lock()
+	b.online = false
+	b.lock.Unlock()
+}
+
+/*
+*
+Removes the expired packets, returns them to be passed to drop once unlocked
+*/
+func (b *sendBuffer) dropExpired(now time.Time) []bufferedPacket {
+	i := 0
+	for i < len(b.packets) && !b.packets[i].expires.IsZero() && now.After(b.packets[i].expires) {
+		i++
+	}
+
+	if i == 0 {
+		return nil
+	}
+
+	utils.Debug("[buffer] expired packets:", i)
+	expired := b.packets[:i]
+	b.packets = b.packets[i:]
+	return expired
+}
+
+func (b *sendBuffer) dropped(packets []bufferedPacket, err error) {
+	for _, p := range packets {
+		b.drop(p.msg, err)
+	}
+}
+
+func (b *sendBuffer) drop(msg *protocol.Message, err error) {
+	if b.onDrop != nil {
+		b.onDrop(msg, err)
+	}
+}
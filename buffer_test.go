@@ -0,0 +1,96 @@
+package socketio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SavvasMohito/go-socket.io-client/protocol"
+)
+
+func bufferedEvent(method string) (*protocol.Message, interface{}) {
+	msg := &protocol.Message{Type: protocol.EVENT, AckId: -1, Method: method}
+	return msg, protocol.GetMsgPacket(msg)
+}
+
+func TestSendBufferOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		overflow OverflowPolicy
+		dropped  string
+		err      error
+		kept     []string
+	}{
+		{DropOldest, "a", nil, []string{"b", "c"}},
+		{DropNewest, "c", nil, []string{"a", "b"}},
+		{OverflowError, "", ErrorSendBufferFull, []string{"a", "b"}},
+	} {
+		var dropped []string
+		b := sendBuffer{size: 2, overflow: tc.overflow, onDrop: func(msg *protocol.Message, err error) {
+			if err != ErrorSendBufferFull {
+				t.Errorf("%v: dropped with %v", tc.overflow, err)
+			}
+			dropped = append(dropped, msg.Method)
+		}}
+
+		var err error
+		for _, method := range []string{"a", "b", "c"} {
+			_, err = b.push(bufferedEvent(method))
+		}
+		if err != tc.err {
+			t.Errorf("%v: got %v, want %v", tc.overflow, err, tc.err)
+		}
+		if tc.dropped != "" && (len(dropped) != 1 || dropped[0] != tc.dropped) {
+			t.Errorf("%v: dropped %v, want %v", tc.overflow, dropped, tc.dropped)
+		}
+		if len(b.packets) != 2 || b.packets[0].msg.Method != tc.kept[0] || b.packets[1].msg.Method != tc.kept[1] {
+			t.Errorf("%v: kept %v", tc.overflow, b.packets)
+		}
+	}
+}
+
+func TestSendBufferExpired(t *testing.T) {
+	var dropped []string
+	b := sendBuffer{size: 10, ttl: 10 * time.Millisecond, onDrop: func(msg *protocol.Message, err error) {
+		if err != ErrorSendBufferExpired {
+			t.Errorf("dropped with %v", err)
+		}
+		dropped = append(dropped, msg.Method)
+	}}
+
+	b.push(bufferedEvent("a"))
+	time.Sleep(20 * time.Millisecond)
+	b.push(bufferedEvent("b"))
+
+	if len(dropped) != 1 || dropped[0] != "a" || len(b.packets) != 1 {
+		t.Fatalf("dropped %v, kept %d", dropped, len(b.packets))
+	}
+}
+
+func TestSendBufferFlushClosed(t *testing.T) {
+	b := sendBuffer{size: 10}
+	for _, method := range []string{"a", "b", "c"} {
+		b.push(bufferedEvent(method))
+	}
+
+	// the connection is closed after the first packet
+	flushed := 0
+	b.setOnline(func(out interface{}) bool {
+		if flushed == 1 {
+			return false
+		}
+		flushed++
+		return true
+	})
+
+	if b.online || len(b.packets) != 2 {
+		t.Fatalf("online %v, kept %d", b.online, len(b.packets))
+	}
+
+	var sent []string
+	b.setOnline(func(out interface{}) bool {
+		sent = append(sent, out.(*protocol.MsgPack).Data.([]interface{})[0].(string))
+		return true
+	})
+	if !b.online || len(sent) != 2 || sent[0] != "b" || sent[1] != "c" {
+		t.Fatalf("online %v, sent %v", b.online, sent)
+	}
+}
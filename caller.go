@@ -4,15 +4,27 @@ import (
 	"errors"
 	"reflect"
 
-	"github.com/mark0725/go-socket.io-client/utils"
+	"github.com/SavvasMohito/go-socket.io-client/parser"
+	"github.com/SavvasMohito/go-socket.io-client/utils"
 )
 
 type caller struct {
 	Func   reflect.Value
 	NumInt int
 	NumOut int
+
+	// func value it was made of, to be removed with Off
+	id uintptr
+	// removed after its first call
+	once bool
+	// handler registered with the generic On, used instead of Func
+	typed func(c *Channel, event string, args []interface{}, ack Ack) error
+	// answers the ack request itself, with an Ack as last argument
+	takesAck bool
 }
 
+var ackType = reflect.TypeOf(Ack(nil))
+
 var (
 	ErrorCallerNotFunc       = errors.New("f is not function")
 	ErrorCallerMaxFiveArgs   = errors.New("f maximum number of args is 5")
@@ -39,9 +51,10 @@ func newCaller(f interface{}) (*caller, error) {
 	}
 
 	curCaller := &caller{
-		Func:   fVal,
-		NumInt: fType.NumIn(),
-		NumOut: fType.NumOut(),
+		Func:     fVal,
+		NumInt:   fType.NumIn(),
+		NumOut:   fType.NumOut(),
+		takesAck: fType.NumIn() > 1 && fType.In(fType.NumIn()-1) == ackType,
 	}
 
 	return curCaller, nil
@@ -55,11 +68,29 @@ func (c *caller) getOutType(index int) interface{} {
 	return reflect.New(c.Func.Type().Out(index)).Interface()
 }
 
-func (c *caller) callFunc(h *Channel, argsType int, args ...interface{}) []reflect.Value {
+/*
+*
+Calls the handler with the arguments of event, the generic handlers
+return an error instead of values. ack answers the ack request of the event.
+*/
+func (c *caller) call(h *Channel, event string, args []interface{}, ack Ack) ([]reflect.Value, error) {
+	if c.typed != nil {
+		return nil, c.typed(h, event, args, ack)
+	}
+
+	return c.callFunc(h, 0, ack, args...), nil
+}
+
+func (c *caller) callFunc(h *Channel, argsType int, ack Ack, args ...interface{}) []reflect.Value {
 	arr := make([]reflect.Value, 0, 1+c.NumInt)
 	arr = append(arr, reflect.ValueOf(h))
 
-	for i := 0; i < c.NumInt-1; i++ { // * 1 2   // x{0} y{1}
+	numArgs := c.NumInt - 1
+	if c.takesAck {
+		numArgs--
+	}
+
+	for i := 0; i < numArgs; i++ { // * 1 2   // x{0} y{1}
 		data := c.getArgType(i + 1)
 
 		if i > len(args)-1 {
@@ -67,6 +98,15 @@ func (c *caller) callFunc(h *Channel, argsType int, args ...interface{}) []refle
 			continue
 		}
 
+		// values that already fit, ps: errors of system events, are passed as is,
+		// with their numbers as float64 like the values decoded into interface{}
+		if argsType == 0 && args[i] != nil {
+			if value := parser.Float64Numbers(args[i]); reflect.TypeOf(value).AssignableTo(c.Func.Type().In(i + 1)) {
+				arr = append(arr, reflect.ValueOf(value))
+				continue
+			}
+		}
+
 		var marshal []byte
 		if argsType == 0 {
 			marshal, _ = utils.Json.Marshal(args[i])
@@ -82,5 +122,9 @@ func (c *caller) callFunc(h *Channel, argsType int, args ...interface{}) []refle
 		arr = append(arr, reflect.ValueOf(data).Elem())
 	}
 
+	if c.takesAck {
+		arr = append(arr, reflect.ValueOf(ack))
+	}
+
 	return c.Func.Call(arr)
 }
@@ -5,19 +5,39 @@ import (
 	"net"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/mark0725/go-socket.io-client/protocol"
-	"github.com/mark0725/go-socket.io-client/utils"
-	"github.com/mark0725/go-socket.io-client/websocket"
+	"github.com/SavvasMohito/go-socket.io-client/protocol"
+	"github.com/SavvasMohito/go-socket.io-client/websocket"
 )
 
 const (
-	queueBufferSize = 10000
+	queueBufferSize        = 10000
+	controlQueueBufferSize = 1000
 )
 const (
 	DefaultCloseTxt  = "transport close"
 	DefaultCloseCode = 101
+
+	// the server sent a DISCONNECT, the client does not reconnect
+	ServerDisconnectTxt  = "io server disconnect"
+	ServerDisconnectCode = 109
+	// Close was called, the client does not reconnect
+	ClientDisconnectTxt  = "io client disconnect"
+	ClientDisconnectCode = 110
+	// the server refused the namespace with a CONNECT_ERROR
+	ConnectErrorTxt  = "connect error"
+	ConnectErrorCode = 111
+	// no ping (v4) or pong (v3) arrived within pingInterval + pingTimeout
+	PingTimeoutTxt  = "ping timeout"
+	PingTimeoutCode = 112
+	// the handlers were overloaded with OverloadDisconnect
+	HandlerOverloadTxt  = "handler overload"
+	HandlerOverloadCode = 113
+	// the server sent a packet which could not be decoded
+	ParseErrorTxt  = "parse error"
+	ParseErrorCode = 114
 )
 
 var (
@@ -35,6 +55,92 @@ type Header struct {
 	PingTimeout  int      `json:"pingTimeout"`
 }
 
+/*
+*
+Outgoing packets of a connection, the write loop sends the control packets,
+ps: pongs, acks and CONNECT, before the data packets waiting meanwhile.
+The DISCONNECT is a data packet, it must not overtake the events before it.
+*/
+type outQueue struct {
+	control chan interface{}
+	data    chan interface{}
+	// closed once the write loop is over
+	done     chan struct{}
+	doneOnce sync.Once
+
+	// onHigh is called once high data packets are queued, onLow once
+	// they are down to low afterwards, high 0 disables them
+	high   int
+	low    int
+	above  atomic.Bool
+	onHigh func()
+	onLow  func()
+}
+
+func newOutQueue(controlSize, dataSize int) *outQueue {
+	return &outQueue{
+		control: make(chan interface{}, controlSize),
+		data:    make(chan interface{}, dataSize),
+		done:    make(chan struct{}),
+	}
+}
+
+/*
+*
+A data packet was queued
+*/
+func (q *outQueue) pushed() {
+	if q.high > 0 && len(q.data) >= q.high && q.above.CompareAndSwap(false, true) {
+		q.onHigh()
+	}
+}
+
+/*
+*
+A data packet was taken by the write loop
+*/
+func (q *outQueue) popped() {
+	if q.high > 0 && len(q.data) <= q.low && q.above.CompareAndSwap(true, false) {
+		q.onLow()
+	}
+}
+
+func (q *outQueue) close() {
+	q.doneOnce.Do(func() {
+		close(q.done)
+	})
+}
+
+/*
+*
+engine.io connection used by a channel, either websocket, long-polling
+or any other transport opened by ClientOptions.Dialer
+
+GetMessage returns the engine.io packets as text, ps: 42["message"], and the
+binary attachments as "b" followed by their bytes. WriteMessage accepts those
+as string and []byte, and *protocol.MsgPack.
+*/
+type TransportConn interface {
+	GetMessage() (message string, err error)
+	WriteMessage(message interface{}) error
+	Close()
+
+	RemoteAddr() net.Addr
+	LocalAddr() net.Addr
+
+	GetProtocol() int
+	GetUseBinaryMessage() bool
+	GetReadBytes() int
+	GetWriteBytes() int
+	PingParams() (interval, timeout time.Duration)
+}
+
+/*
+*
+Opens a connection to the engine.io url, ps: ws://host/socket.io/?EIO=4&transport=websocket
+*/
+type Dialer func(url string) (TransportConn, error)
+
 /*
 *
 socket.io connection handler
@@ -46,50 +152,161 @@ Close message means channel is closed
 ping is automatic
 */
 type Channel struct {
-	conn      *websocket.Connection
+	conn      TransportConn
 	namespace string
 
-	out    chan interface{}
+	out    *outQueue
 	header Header
 
 	alive     bool
 	aliveLock sync.Mutex
 
-	ack ackProcessor
+	ack    ackProcessor
+	buffer sendBuffer
+
+	anyOutgoing anyListeners
+
+	// connection state recovery, the session id and the offset
+	// of the last event are sent back when connecting again
+	pid         string
+	offset      string
+	recovered   bool
+	sessionLock sync.Mutex
 
 	ip      string
 	request *http.Request
 }
 
 func (c *Channel) BinaryMessage() bool {
-	return c.conn.GetUseBinaryMessage()
+	return c.getConn().GetUseBinaryMessage()
 }
 
 func (c *Channel) RemoteAddr() net.Addr {
-	return c.conn.RemoteAddr()
+	return c.getConn().RemoteAddr()
 }
 
 func (c *Channel) LocalAddr() net.Addr {
-	return c.conn.LocalAddr()
+	return c.getConn().LocalAddr()
 }
 
-func (c *Channel) initChannel() {
-	//TODO: queueBufferSize from constant to server or socket variable
-	c.out = make(chan interface{}, queueBufferSize)
+func (c *Channel) initChannel(conn TransportConn, out *outQueue) {
+	c.aliveLock.Lock()
+	c.out = out
 	//c.ack.resultWaiters = make(map[int](chan string))
-	c.setAliveValue(true)
+	c.conn = conn
+	c.alive = true
+	c.aliveLock.Unlock()
+}
+
+/*
+*
+Returns the current connection, it is shared by the namespaces
+of a manager and replaced on every reconnection
+*/
+func (c *Channel) getConn() TransportConn {
+	c.aliveLock.Lock()
+	conn := c.conn
+	c.aliveLock.Unlock()
+
+	return conn
+}
+
+/*
+*
+Returns the outgoing queue of the current connection
+*/
+func (c *Channel) queue() *outQueue {
+	c.aliveLock.Lock()
+	out := c.out
+	c.aliveLock.Unlock()
+
+	return out
 }
 
 func (c *Channel) Id() string {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+
 	return c.header.Sid
 }
 
+/*
+*
+Stores the engine.io header of the OPEN packet
+*/
+func (c *Channel) setHeader(header Header) {
+	c.sessionLock.Lock()
+	c.header = header
+	c.sessionLock.Unlock()
+}
+
+func (c *Channel) getHeader() Header {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+
+	return c.header
+}
+
+/*
+*
+Checks that the server restored the session of the previous connection,
+so the rooms are kept and the missed events have been replayed
+*/
+func (c *Channel) Recovered() bool {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+
+	return c.recovered
+}
+
+/*
+*
+Stores the ids received with the namespace CONNECT, the session is
+recovered when the server hands back the previous pid
+*/
+func (c *Channel) setSession(sid, pid string) {
+	c.sessionLock.Lock()
+	c.header.Sid = sid
+	c.recovered = pid != "" && pid == c.pid
+	c.pid = pid
+	c.sessionLock.Unlock()
+}
+
+/*
+*
+Keeps the offset of the last event, the server appends it as last argument
+when recovery is enabled. Without a pid recovery is disabled, and the
+last argument is not an offset.
+*/
+func (c *Channel) setOffset(offset string) {
+	c.sessionLock.Lock()
+	if c.pid != "" {
+		c.offset = offset
+	}
+	c.sessionLock.Unlock()
+}
+
+func (c *Channel) recoveryState() (pid string, offset string) {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+
+	return c.pid, c.offset
+}
+
+func (c *Channel) resetRecovery() {
+	c.sessionLock.Lock()
+	c.pid = ""
+	c.offset = ""
+	c.recovered = false
+	c.sessionLock.Unlock()
+}
+
 func (c *Channel) ReadBytes() int {
-	return c.conn.GetReadBytes()
+	return c.getConn().GetReadBytes()
 }
 
 func (c *Channel) WriteBytes() int {
-	return c.conn.GetWriteBytes()
+	return c.getConn().GetWriteBytes()
 }
 
 /*
@@ -112,7 +329,7 @@ func (c *Channel) setAliveValue(value bool) {
 
 /*
 *
-Close channel
+Close channel of a namespace, the engine.io connection is closed by the manager
 */
 func closeChannel(c *Channel, m *methods, args ...interface{}) error {
 	if !c.IsAlive() {
@@ -120,6 +337,7 @@ func closeChannel(c *Channel, m *methods, args ...interface{}) error {
 		return nil
 	}
 	c.setAliveValue(false)
+	c.buffer.setOffline()
 
 	var s []interface{}
 	closeErr := &websocket.CloseError{}
@@ -133,109 +351,28 @@ func closeChannel(c *Channel, m *methods, args ...interface{}) error {
 		s = append(s, args...)
 	}
 
-	c.conn.Close()
-	c.out <- protocol.CloseMsg
-
 	m.callLoopEvent(c, OnDisconnection, s...)
 
 	return nil
 }
 
-// incoming messages loop, puts incoming messages to In channel
-func inLoop(c *Channel, m *methods) error {
-	for {
-		msg, err := c.conn.GetMessage()
-		if err != nil {
-			return closeChannel(c, m, err)
-		}
-
-		prefix := string(msg[0])
-		protocolV := c.conn.GetProtocol()
-
-		switch prefix {
-		case protocol.OpenMsg:
-			if err := utils.Json.UnmarshalFromString(msg[1:], &c.header); err != nil {
-				closeErr := &websocket.CloseError{}
-				closeErr.Code = websocket.ParseOpenMsgCode
-				closeErr.Text = err.Error()
-
-				return closeChannel(c, m, closeErr)
-			}
-
-			if protocolV == protocol.Protocol3 {
-				m.callLoopEvent(c, OnConnection)
-				// in protocol v3, the client sends a ping, and the server answers with a pong
-				go SchedulePing(c)
-			}
-
-			if c.conn.GetProtocol() == protocol.Protocol4 {
-				// in protocol v4 & binary msg Connection to a namespace
-				if c.conn.GetUseBinaryMessage() {
-					c.out <- &protocol.MsgPack{
-						Type: protocol.CONNECT,
-						Nsp:  protocol.DefaultNsp,
-						Data: nil,
-					}
-
-					// in protocol v4 & text msg Connection to a namespace
-				} else {
-					c.out <- protocol.CommonMsg + protocol.OpenMsg
-				}
-			}
-		case protocol.CloseMsg:
-			return closeChannel(c, m)
-		case protocol.PingMsg:
-			// in protocol v4, the server sends a ping, and the client answers with a pong
-			c.out <- protocol.PongMsg
-		case protocol.PongMsg:
-		case protocol.UpgradeMsg:
-		case protocol.CommonMsg:
-			// in protocol v3 & binary msg  ps: 4{"type":0,"data":null,"nsp":"/","id":0}
-			// in protocol v3 & text msg  ps: 40 or 41 or 42["message", ...]
-			// in protocol v4 & text msg  ps: 40 or 41 or 42["message", ...]
-			go m.processIncomingMessage(c, msg[1:])
-		default:
-			// in protocol v4 & binary msg ps: {"type":0,"data":{"sid":"HWEr440000:1:R1CHyink:shadiao:101"},"nsp":"/","id":0}
-			go m.processIncomingMessage(c, msg)
-		}
-	}
-}
-
-func outLoop(c *Channel, m *methods) error {
-	for {
-		outBufferLen := len(c.out)
-		if outBufferLen >= queueBufferSize-1 {
-			closeErr := &websocket.CloseError{}
-			closeErr.Code = websocket.QueueBufferSizeCode
-			closeErr.Text = ErrorSocketOverflood.Error()
-
-			closeChannel(c, m, closeErr)
-		}
-
-		msg := <-c.out
-		if msg == protocol.CloseMsg {
-			return nil
-		}
-
-		err := c.conn.WriteMessage(msg)
-		if err != nil {
-			closeErr := &websocket.CloseError{}
-			closeErr.Code = websocket.WriteBufferErrCode
-			closeErr.Text = err.Error()
-
-			closeChannel(c, m, closeErr)
-		}
-	}
-}
+/*
+*
+Sends a ping every ping interval while c is connected.
 
+Deprecated: the Manager schedules the pings of protocol v3 itself, from the
+values of the OPEN packet, and in protocol v4 the server sends them.
+*/
 func SchedulePing(c *Channel) {
-	interval, _ := c.conn.PingParams()
+	conn := c.getConn()
+	interval, _ := pingParams(conn, c.getHeader())
 	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 	for {
 		<-ticker.C
-		if !c.IsAlive() {
+		if !c.IsAlive() || c.getConn() != conn {
 			return
 		}
-		c.out <- protocol.PingMsg
+		c.queue().control <- protocol.PingMsg
 	}
 }
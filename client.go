@@ -1,15 +1,16 @@
 package socketio
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
+	"net/http"
 	"net/url"
-	"strings"
+	"sync/atomic"
 	"time"
 
-	"github.com/mark0725/go-socket.io-client/protocol"
-	"github.com/mark0725/go-socket.io-client/utils"
-	"github.com/mark0725/go-socket.io-client/websocket"
+	"github.com/SavvasMohito/go-socket.io-client/protocol"
+	"github.com/SavvasMohito/go-socket.io-client/utils"
+	"github.com/SavvasMohito/go-socket.io-client/websocket"
 )
 
 // const (
@@ -24,31 +25,141 @@ const (
 	rootNamespace      = ""
 )
 
+// engine.io transports
+const (
+	TransportPolling   = "polling"
+	TransportWebsocket = "websocket"
+)
+
+var (
+	ErrorUnknownTransport = errors.New("unknown transport")
+)
+
 type ClientOptions struct {
 	Namespace string
 	Path      string
 	Auth      map[string]string
 	//IOOpts    *engineio.Options
+
+	// AuthFunc returns the auth payload of the namespace CONNECT, it is called on
+	// every connection attempt and takes precedence over Auth.
+	// Any value that marshals to a JSON object may be returned.
+	AuthFunc func() (interface{}, error)
+	// HeaderFunc returns the headers of the handshake request, it is called
+	// every time the engine.io connection is opened
+	HeaderFunc func() (http.Header, error)
+	// IsAuthError tells whether a CONNECT_ERROR is an authentication failure,
+	// the credentials are then refreshed and the CONNECT sent once more.
+	// Defaults to looking for words such as "auth" or "token" in the message.
+	IsAuthError func(err *ConnectError) bool
+
+	// Transports in order of preference, the connection is opened with the first one.
+	// Starting with polling upgrades to websocket when websocket is also listed.
+	// Defaults to websocket only.
+	Transports []string
+	// Dialer opens the connections instead of the websocket and polling transports,
+	// ps: the in-memory pipe of the pipe package
+	Dialer Dialer
+
+	// Reconnection reopens the connection when it is lost, unless it was closed
+	// by the client or the server sent a DISCONNECT.
+	Reconnection bool
+	// ReconnectionAttempts before giving up, 0 retries forever
+	ReconnectionAttempts int
+	// ReconnectionDelay before the first attempt, doubled on every next one
+	ReconnectionDelay time.Duration
+	// ReconnectionDelayMax caps the delay between two attempts
+	ReconnectionDelayMax time.Duration
+	// RandomizationFactor in [0, 1] spreads the delay by up to that fraction
+	RandomizationFactor float64
+
+	// SendBufferSize packets emitted while the namespace is not connected are held
+	// and sent in order once it is, 0 disables the buffer and such packets are lost
+	SendBufferSize int
+	// SendBufferTTL drops packets held for longer, 0 keeps them until they are sent
+	SendBufferTTL time.Duration
+	// SendBufferOverflow decides which packet is lost when the buffer is full,
+	// the handlers of OnBufferDrop are called for each lost packet and
+	// its ack fails with ErrorSendBufferFull or ErrorSendBufferExpired
+	SendBufferOverflow OverflowPolicy
+
+	// ConnectTimeout bounds ConnectContext until the namespace is accepted
+	ConnectTimeout time.Duration
+
+	// AckReconnectRetries keeps the acks pending when the connection is lost
+	// and sends them again after the reconnection, up to that many times.
+	// 0 fails them right away with ErrorDisconnected.
+	AckReconnectRetries int
+
+	// Retries of the events emitted with EmitReliable, sent again when they
+	// are not acknowledged within AckTimeout
+	Retries int
+	// AckTimeout before an event emitted with EmitReliable is sent again
+	AckTimeout time.Duration
+	// RetryDelay before the first retry, doubled on every next one
+	RetryDelay time.Duration
+	// RetryDelayMax caps the delay between two retries
+	RetryDelayMax time.Duration
+
+	// DispatchMode orders the calls of the handlers, they run concurrently by default
+	DispatchMode DispatchMode
+	// DispatchKey returns the key of an event with DispatchPerKey,
+	// ps: the id of the order book of an update
+	DispatchKey func(event string, args ...interface{}) string
+	// MaxHandlers events are handled at the same time, 0 is unbounded,
+	// the other packets, ps: CONNECT and DISCONNECT, do not wait for a handler
+	MaxHandlers int
+	// HandlerQueueSize events at most wait for a handler, 0 is unbounded,
+	// beyond it HandlerOverload applies and OnOverload is called
+	HandlerQueueSize int
+	// HandlerOverload decides what happens to the events beyond HandlerQueueSize
+	HandlerOverload OverloadPolicy
+
+	// ControlQueueSize outgoing control packets, ps: pongs and acks, are queued
+	// at most, they are sent before the data packets
+	ControlQueueSize int
+	// DataQueueSize outgoing events are queued at most, then Emit and TryEmit
+	// fail with ErrorSocketOverflood and EmitContext waits for room
+	DataQueueSize int
+	// HighWatermark queued outgoing events call OnHighWatermark, 0 disables it
+	HighWatermark int
+	// LowWatermark queued outgoing events call OnLowWatermark once the queue
+	// drains after HighWatermark, defaults to half HighWatermark
+	LowWatermark int
 }
 
 type Client struct {
 	namespace string
-	url       string
-	path      string
 	auth      map[string]string
+	authFunc  func() (interface{}, error)
+
+	isAuthError func(err *ConnectError) bool
+	// the CONNECT was sent again after an authentication failure
+	authRetried atomic.Bool
+
+	manager *Manager
+	// the namespace is connected or connecting, guarded by the manager lock
+	active bool
+
+	connectWaiters connectWaiters
+	connectTimeout time.Duration
 
 	//tr websocket.Transport
 	//handlers *namespaceHandlers
-	handlers methods
-	channel  Channel
+	handlers   methods
+	channel    Channel
+	dispatcher dispatcher
 }
 
+/*
+*
+Returns the client of the namespace of addr, or of opts.Namespace,
+over its own engine.io connection
+*/
 func NewClient(addr string, opts *ClientOptions) (*Client, error) {
-	c := &Client{}
-
-	var err error
-	if addr == "" {
-		return nil, errors.New("EmptyAddrErr")
+	m, err := NewManager(addr, opts)
+	if err != nil {
+		return nil, err
 	}
 
 	u, err := url.Parse(addr)
@@ -56,185 +167,322 @@ func NewClient(addr string, opts *ClientOptions) (*Client, error) {
 		return nil, err
 	}
 
-	switch u.Scheme {
-	case "http":
-		u.Scheme = "ws"
-	case "https":
-		u.Scheme = "wss"
+	namespace := u.Path
+	if opts.Namespace != "" {
+		namespace = opts.Namespace
 	}
 
-	namespace := fmtNS(u.Path)
-	if opts.Namespace != "" {
-		namespace = fmtNS(opts.Namespace)
+	return m.Socket(namespace, opts), nil
+}
+
+func newClient(m *Manager, namespace string, opts *ClientOptions) *Client {
+	c := &Client{
+		namespace: namespace,
+		manager:   m,
 	}
-	c.namespace = namespace
 	c.channel.namespace = namespace
 
-	u.Path = "/socket.io"
-	if opts.Path != "" {
-		u.Path = opts.Path
+	if opts.Auth != nil {
+		c.auth = opts.Auth
 	}
+	c.authFunc = opts.AuthFunc
 
-	u.Path = u.EscapedPath()
-	if !strings.HasSuffix(u.Path, "/") {
-		u.Path += "/"
+	c.connectTimeout = defaultConnectTimeout
+	if opts.ConnectTimeout > 0 {
+		c.connectTimeout = opts.ConnectTimeout
 	}
-	c.path = u.Path
 
-	c.url = u.String()
-	utils.Debug("url:", c.url)
+	c.isAuthError = isAuthError
+	if opts.IsAuthError != nil {
+		c.isAuthError = opts.IsAuthError
+	}
 
-	if opts.Auth != nil {
-		c.auth = opts.Auth
+	c.dispatcher = dispatcher{
+		mode:       opts.DispatchMode,
+		key:        opts.DispatchKey,
+		maxWorkers: opts.MaxHandlers,
+		queueSize:  opts.HandlerQueueSize,
+		overload:   opts.HandlerOverload,
 	}
 
-	return c, nil
+	c.channel.ack.retries = opts.AckReconnectRetries
+	c.channel.ack.queue = retryQueue{
+		retries: opts.Retries,
+		timeout: defaultAckTimeout,
+		backoff: backoff{
+			min:    defaultRetryDelay,
+			max:    defaultRetryDelayMax,
+			jitter: opts.RandomizationFactor,
+		},
+	}
+	if opts.AckTimeout > 0 {
+		c.channel.ack.queue.timeout = opts.AckTimeout
+	}
+	if opts.RetryDelay > 0 {
+		c.channel.ack.queue.backoff.min = opts.RetryDelay
+	}
+	if opts.RetryDelayMax > 0 {
+		c.channel.ack.queue.backoff.max = opts.RetryDelayMax
+	}
+
+	c.channel.buffer = sendBuffer{
+		size:     opts.SendBufferSize,
+		ttl:      opts.SendBufferTTL,
+		overflow: opts.SendBufferOverflow,
+		onDrop:   c.onBufferDrop,
+	}
+
+	c.handlers.onConnection = c.onConnection
+	c.handlers.onDisconnection = c.onDisconnection
+	c.handlers.onConnectError = c.onConnectError
+
+	return c
 }
 
+/*
+*
+Returns the manager of the engine.io connection used by the client
+*/
+func (c *Client) Manager() *Manager {
+	return c.manager
+}
+
+/*
+*
+Opens the connection and sends the namespace CONNECT without waiting
+for the server to accept it, see ConnectContext
+*/
 func (c *Client) Connect() error {
-	var err error
-	tr := websocket.GetDefaultWebsocketTransport()
+	return c.manager.connect(c)
+}
 
-	u, err := url.Parse(c.url)
-	if err != nil {
-		return err
+/*
+*
+Disconnects the namespace, the engine.io connection is closed
+when no other namespace of the manager is connected
+*/
+func (c *Client) Close() {
+	if c.channel.IsAlive() {
+		c.sendDisconnect()
 	}
 
-	queryParams := u.Query()
-	queryParams.Set("transport", "websocket")
+	closeErr := &websocket.CloseError{}
+	closeErr.Code = ClientDisconnectCode
+	closeErr.Text = ClientDisconnectTxt
 
-	if tr.Protocol == protocol.Protocol3 {
-		queryParams.Set("EIO", "3")
-	} else if tr.Protocol == protocol.Protocol4 {
-		queryParams.Set("EIO", "4")
-	} else {
-		queryParams.Set("EIO", "4")
-	}
-
-	queryParams.Set("t", time.Now().Format("02150405"))
+	closeChannel(&c.channel, &c.handlers, closeErr)
+	// the acks kept while reconnecting, the channel was already closed
+	c.channel.ack.disconnected(false)
+	c.channel.resetRecovery()
+	c.manager.destroy(c)
+}
 
-	u.RawQuery = queryParams.Encode()
+/*
+*
+Returns true when the server restored the previous session on reconnection
+*/
+func (c *Client) Recovered() bool {
+	return c.channel.Recovered()
+}
 
-	eioAddr := u.String()
-	utils.Debug("[sockio-client] full addr: ", eioAddr)
+/*
+*
+Connects to the namespace, in protocol v3 the default namespace
+is connected along with the engine.io connection
+*/
+func (c *Client) sendConnect() {
+	conn := c.channel.getConn()
+	protocolV := conn.GetProtocol()
+
+	if protocolV == protocol.Protocol3 && c.namespace == rootNamespace {
+		c.handlers.callLoopEvent(&c.channel, OnConnection)
+		return
+	}
 
-	c.channel.initChannel()
-	c.channel.conn, err = tr.Connect(eioAddr)
+	auth, err := c.connectAuth()
 	if err != nil {
-		return err
+		utils.Debug("[auth] credentials:", err)
+		connectErr := &ConnectError{Message: err.Error()}
+		c.connectWaiters.notify(connectErr)
+		c.handlers.callLoopEvent(&c.channel, OnConnectError, connectErr)
+
+		closeErr := &websocket.CloseError{}
+		closeErr.Code = ConnectErrorCode
+		closeErr.Text = err.Error()
+
+		closeChannel(&c.channel, &c.handlers, closeErr)
+		return
 	}
 
-	go c.clientRead()
-	go c.clientWrite()
+	// in protocol v3 the auth payload is not supported by text msg
+	if protocolV == protocol.Protocol3 && !conn.GetUseBinaryMessage() {
+		auth = nil
+	}
 
-	return nil
+	// Connection to a namespace ps: 40/admin,{"token":"123"}
+	c.channel.queue().control <- &protocol.MsgPack{
+		Type: protocol.CONNECT,
+		Nsp:  c.namespace,
+		Data: auth,
+	}
 }
 
-func (c *Client) Close() {
-	closeChannel(&c.channel, &c.handlers)
-}
+/*
+*
+Lets the server know that the namespace is disconnected, the DISCONNECT
+is queued behind the events so that they are sent before it
+*/
+func (c *Client) sendDisconnect() {
+	queue := c.channel.queue()
+	// ps: 41/admin,
+	msg := &protocol.MsgPack{
+		Type: protocol.DISCONNECT,
+		Nsp:  c.namespace,
+	}
 
-func (c *Client) On(method string, f interface{}) error {
-	return c.handlers.On(method, f)
+	select {
+	case queue.data <- msg:
+		queue.pushed()
+	case <-queue.done:
+	}
 }
 
-func (c *Client) Emit(method string, args ...interface{}) error {
-	return c.channel.Emit(method, args...)
+/*
+*
+System handler of OnConnection, sends again the acks pending on disconnection,
+flushes the packets emitted while offline and resumes EmitReliable
+*/
+func (c *Client) onConnection(ch *Channel, args ...interface{}) {
+	c.authRetried.Store(false)
+	queue := ch.queue()
+	flush := func(out interface{}) bool {
+		select {
+		case queue.data <- out:
+			queue.pushed()
+			return true
+		case <-queue.done:
+			return false
+		}
+	}
+	ch.ack.resend(func(msg *protocol.Message) {
+		flush(protocol.GetMsgPacket(msg))
+	})
+	ch.ack.queue.resume()
+	ch.buffer.setOnline(flush)
+	ch.ack.queue.drain(ch)
+	c.connectWaiters.notify(nil)
 }
 
-func (c *Client) clientRead() error {
-	for {
-		msg, err := c.channel.conn.GetMessage()
-		if err != nil {
-			return closeChannel(&c.channel, &c.handlers, err)
+/*
+*
+A buffered packet is lost, its ack fails with the reason
+and the handlers of OnBufferDrop are called
+*/
+func (c *Client) onBufferDrop(msg *protocol.Message, err error) {
+	if msg.AckId >= 0 {
+		if waiter, werr := c.channel.ack.getWaiter(msg.AckId); werr == nil {
+			waiter.resolve(nil, nil, err)
 		}
+	}
 
-		prefix := string(msg[0])
-		protocolV := c.channel.conn.GetProtocol()
+	go c.handlers.callLoopEvent(&c.channel, OnBufferDrop, &DropError{Event: msg.Method, Err: err})
+}
 
-		switch prefix {
-		case protocol.OpenMsg:
-			if err := utils.Json.UnmarshalFromString(msg[1:], &c.channel.header); err != nil {
-				closeErr := &websocket.CloseError{}
-				closeErr.Code = websocket.ParseOpenMsgCode
-				closeErr.Text = err.Error()
+/*
+*
+System handler of OnDisconnection, the namespace is not connected again
+once the server disconnected it or refused it, the pending acks fail
+unless they are sent again after the reconnection
+*/
+func (c *Client) onDisconnection(ch *Channel, args ...interface{}) {
+	if len(args) == 0 {
+		ch.ack.disconnected(false)
+		return
+	}
 
-				return closeChannel(&c.channel, &c.handlers, closeErr)
-			}
+	c.connectWaiters.setDisconnected()
 
-			if protocolV == protocol.Protocol3 {
-				c.handlers.callLoopEvent(&c.channel, OnConnection)
-				// in protocol v3, the client sends a ping, and the server answers with a pong
-				go SchedulePing(&c.channel)
+	closeErr, ok := args[0].(*websocket.CloseError)
+	if !ok {
+		ch.ack.disconnected(c.manager.reconnection)
+		// an error of the transport, ps: io.EOF, unless the connection is retried
+		if !c.manager.reconnection {
+			err, _ := args[0].(error)
+			if err == nil {
+				err = ErrorNotConnected
 			}
+			c.connectWaiters.notify(err)
+		}
+		return
+	}
 
-			if c.channel.conn.GetProtocol() == protocol.Protocol4 {
-
-				// in protocol v4 & binary msg Connection to a namespace
-				if c.channel.conn.GetUseBinaryMessage() {
-					c.channel.out <- &protocol.MsgPack{
-						Type: protocol.CONNECT,
-						//Nsp:  protocol.DefaultNsp,
-						Nsp:  c.namespace,
-						Data: c.auth,
-					}
-
-					// in protocol v4 & text msg Connection to a namespace
-				} else {
-					replyMsg := protocol.CommonMsg + protocol.OpenMsg + c.namespace
-					if c.auth != nil {
-						jsonData, _ := json.Marshal(c.auth)
-						dataText := string(jsonData)
-						replyMsg = replyMsg + dataText
-					}
-
-					c.channel.out <- replyMsg
-				}
-			}
-		case protocol.CloseMsg:
-			return closeChannel(&c.channel, &c.handlers)
-		case protocol.PingMsg:
-			// in protocol v4, the server sends a ping, and the client answers with a pong
-			c.channel.out <- protocol.PongMsg
-		case protocol.PongMsg:
-		case protocol.UpgradeMsg:
-		case protocol.CommonMsg:
-			// in protocol v3 & binary msg  ps: 4{"type":0,"data":null,"nsp":"/","id":0}
-			// in protocol v3 & text msg  ps: 40 or 41 or 42["message", ...]
-			// in protocol v4 & text msg  ps: 40 or 41 or 42["message", ...]
-			go c.handlers.processIncomingMessage(&c.channel, msg[1:])
-		default:
-			// in protocol v4 & binary msg ps: {"type":0,"data":{"sid":"HWEr440000:1:R1CHyink:shadiao:101"},"nsp":"/","id":0}
-			go c.handlers.processIncomingMessage(&c.channel, msg)
+	switch closeErr.Code {
+	case ServerDisconnectCode, ConnectErrorCode:
+		ch.ack.disconnected(false)
+		c.manager.destroy(c)
+		c.connectWaiters.notify(closeErr)
+	case ClientDisconnectCode:
+		ch.ack.disconnected(false)
+		c.connectWaiters.notify(closeErr)
+	default:
+		ch.ack.disconnected(c.manager.reconnection)
+		// ConnectContext keeps waiting while the connection is retried
+		if !c.manager.reconnection {
+			c.connectWaiters.notify(closeErr)
 		}
 	}
 }
 
-func (c *Client) clientWrite() error {
-	for {
-		outBufferLen := len(c.channel.out)
-		if outBufferLen >= queueBufferSize-1 {
-			closeErr := &websocket.CloseError{}
-			closeErr.Code = websocket.QueueBufferSizeCode
-			closeErr.Text = ErrorSocketOverflood.Error()
+func (c *Client) On(method string, f interface{}) error {
+	return c.handlers.On(method, f)
+}
+
+func (c *Client) Once(method string, f interface{}) error {
+	return c.handlers.Once(method, f)
+}
 
-			closeChannel(&c.channel, &c.handlers, closeErr)
-		}
+/*
+*
+Removes the listener of method added with the func value f
+*/
+func (c *Client) Off(method string, f interface{}) {
+	c.handlers.Off(method, f)
+}
 
-		msg := <-c.channel.out
-		if msg == protocol.CloseMsg {
-			return nil
-		}
+/*
+*
+Removes the listeners of the given methods, of every method when none is given
+*/
+func (c *Client) RemoveAllListeners(methods ...string) {
+	c.handlers.RemoveAllListeners(methods...)
+}
 
-		err := c.channel.conn.WriteMessage(msg)
-		if err != nil {
-			closeErr := &websocket.CloseError{}
-			closeErr.Code = websocket.WriteBufferErrCode
-			closeErr.Text = err.Error()
+func (c *Client) ListenerCount(method string) int {
+	return c.handlers.ListenerCount(method)
+}
 
-			closeChannel(&c.channel, &c.handlers, closeErr)
-		}
-	}
+func (c *Client) Emit(method string, args ...interface{}) error {
+	return c.channel.Emit(method, args...)
+}
+
+func (c *Client) EmitContext(ctx context.Context, method string, args ...interface{}) error {
+	return c.channel.EmitContext(ctx, method, args...)
+}
+
+func (c *Client) TryEmit(method string, args ...interface{}) error {
+	return c.channel.TryEmit(method, args...)
+}
+
+func (c *Client) EmitWithAck(method string, args ...interface{}) *AckFuture {
+	return c.channel.EmitWithAck(method, args...)
+}
+
+func (c *Client) EmitReliable(method string, args ...interface{}) *AckFuture {
+	return c.channel.EmitReliable(method, args...)
+}
+
+func (c *Client) Ack(method string, timeout time.Duration, args ...interface{}) (interface{}, error) {
+	return c.channel.Ack(method, timeout, args...)
 }
 
 func fmtNS(ns string) string {
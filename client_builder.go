@@ -1,5 +1,10 @@
 package socketio
 
+import (
+	"net/http"
+	"time"
+)
+
 type ClientBuilder struct{}
 
 type ClientOption func(*ClientOptions)
@@ -22,6 +27,164 @@ func (c *ClientBuilder) WithAuth(v map[string]string) ClientOption {
 	}
 }
 
+func (c *ClientBuilder) WithAuthFunc(v func() (interface{}, error)) ClientOption {
+	return func(c *ClientOptions) {
+		c.AuthFunc = v
+	}
+}
+
+func (c *ClientBuilder) WithHeaderFunc(v func() (http.Header, error)) ClientOption {
+	return func(c *ClientOptions) {
+		c.HeaderFunc = v
+	}
+}
+
+func (c *ClientBuilder) WithIsAuthError(v func(err *ConnectError) bool) ClientOption {
+	return func(c *ClientOptions) {
+		c.IsAuthError = v
+	}
+}
+
+func (c *ClientBuilder) WithDialer(v Dialer) ClientOption {
+	return func(c *ClientOptions) {
+		c.Dialer = v
+	}
+}
+
+func (c *ClientBuilder) WithTransports(v ...string) ClientOption {
+	return func(c *ClientOptions) {
+		c.Transports = v
+	}
+}
+
+func (c *ClientBuilder) WithReconnection(v bool) ClientOption {
+	return func(c *ClientOptions) {
+		c.Reconnection = v
+	}
+}
+
+func (c *ClientBuilder) WithReconnectionAttempts(v int) ClientOption {
+	return func(c *ClientOptions) {
+		c.ReconnectionAttempts = v
+	}
+}
+
+func (c *ClientBuilder) WithReconnectionDelay(v time.Duration) ClientOption {
+	return func(c *ClientOptions) {
+		c.ReconnectionDelay = v
+	}
+}
+
+func (c *ClientBuilder) WithReconnectionDelayMax(v time.Duration) ClientOption {
+	return func(c *ClientOptions) {
+		c.ReconnectionDelayMax = v
+	}
+}
+
+func (c *ClientBuilder) WithRandomizationFactor(v float64) ClientOption {
+	return func(c *ClientOptions) {
+		c.RandomizationFactor = v
+	}
+}
+
+func (c *ClientBuilder) WithSendBuffer(size int, ttl time.Duration, overflow OverflowPolicy) ClientOption {
+	return func(c *ClientOptions) {
+		c.SendBufferSize = size
+		c.SendBufferTTL = ttl
+		c.SendBufferOverflow = overflow
+	}
+}
+
+func (c *ClientBuilder) WithConnectTimeout(v time.Duration) ClientOption {
+	return func(c *ClientOptions) {
+		c.ConnectTimeout = v
+	}
+}
+
+func (c *ClientBuilder) WithAckReconnectRetries(v int) ClientOption {
+	return func(c *ClientOptions) {
+		c.AckReconnectRetries = v
+	}
+}
+
+func (c *ClientBuilder) WithRetries(v int) ClientOption {
+	return func(c *ClientOptions) {
+		c.Retries = v
+	}
+}
+
+func (c *ClientBuilder) WithAckTimeout(v time.Duration) ClientOption {
+	return func(c *ClientOptions) {
+		c.AckTimeout = v
+	}
+}
+
+func (c *ClientBuilder) WithRetryDelay(v time.Duration) ClientOption {
+	return func(c *ClientOptions) {
+		c.RetryDelay = v
+	}
+}
+
+func (c *ClientBuilder) WithRetryDelayMax(v time.Duration) ClientOption {
+	return func(c *ClientOptions) {
+		c.RetryDelayMax = v
+	}
+}
+
+func (c *ClientBuilder) WithDispatchMode(v DispatchMode) ClientOption {
+	return func(c *ClientOptions) {
+		c.DispatchMode = v
+	}
+}
+
+func (c *ClientBuilder) WithDispatchKey(v func(event string, args ...interface{}) string) ClientOption {
+	return func(c *ClientOptions) {
+		c.DispatchKey = v
+	}
+}
+
+func (c *ClientBuilder) WithMaxHandlers(v int) ClientOption {
+	return func(c *ClientOptions) {
+		c.MaxHandlers = v
+	}
+}
+
+func (c *ClientBuilder) WithHandlerQueueSize(v int) ClientOption {
+	return func(c *ClientOptions) {
+		c.HandlerQueueSize = v
+	}
+}
+
+func (c *ClientBuilder) WithHandlerOverload(v OverloadPolicy) ClientOption {
+	return func(c *ClientOptions) {
+		c.HandlerOverload = v
+	}
+}
+
+func (c *ClientBuilder) WithControlQueueSize(v int) ClientOption {
+	return func(c *ClientOptions) {
+		c.ControlQueueSize = v
+	}
+}
+
+func (c *ClientBuilder) WithDataQueueSize(v int) ClientOption {
+	return func(c *ClientOptions) {
+		c.DataQueueSize = v
+	}
+}
+
+func (c *ClientBuilder) WithHighWatermark(v int) ClientOption {
+	return func(c *ClientOptions) {
+		c.HighWatermark = v
+	}
+}
+
+func (c *ClientBuilder) WithLowWatermark(v int) ClientOption {
+	return func(c *ClientOptions) {
+		c.LowWatermark = v
+	}
+}
+
 func (c *ClientBuilder) WithIOOpts(v map[string]string) ClientOption {
 	return func(c *ClientOptions) {
 		c.Auth = v
@@ -31,8 +194,11 @@ func (c *ClientBuilder) WithIOOpts(v map[string]string) ClientOption {
 func (c *ClientBuilder) Build(addr string, opts ...ClientOption) (*Client, error) {
 	// 设置默认值
 	clientOptions := &ClientOptions{
-		Namespace: "",
-		Auth:      nil,
+		Namespace:            "",
+		Auth:                 nil,
+		ReconnectionDelay:    defaultReconnectionDelay,
+		ReconnectionDelayMax: defaultReconnectionDelayMax,
+		RandomizationFactor:  defaultRandomizationFactor,
 		//IOOpts:    nil,
 	}
 
@@ -45,3 +211,21 @@ func (c *ClientBuilder) Build(addr string, opts ...ClientOption) (*Client, error
 
 	return client, err
 }
+
+/*
+*
+Returns a manager, use its Socket method to get the client of each namespace
+*/
+func (c *ClientBuilder) BuildManager(addr string, opts ...ClientOption) (*Manager, error) {
+	clientOptions := &ClientOptions{
+		ReconnectionDelay:    defaultReconnectionDelay,
+		ReconnectionDelayMax: defaultReconnectionDelayMax,
+		RandomizationFactor:  defaultRandomizationFactor,
+	}
+
+	for _, opt := range opts {
+		opt(clientOptions)
+	}
+
+	return NewManager(addr, clientOptions)
+}
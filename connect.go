@@ -0,0 +1,111 @@
+package socketio
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultConnectTimeout = 20 * time.Second
+)
+
+var (
+	ErrorConnectTimeout  = errors.New("connect timeout")
+	ErrorReconnectFailed = errors.New("reconnect failed")
+)
+
+/*
+*
+ConnectContext calls waiting for the outcome of the namespace CONNECT
+*/
+type connectWaiters struct {
+	// the namespace was accepted and is not disconnected since
+	connected bool
+	waiters   map[chan error]struct{}
+	lock      sync.Mutex
+}
+
+/*
+*
+Returns a channel told the outcome of the next namespace CONNECT,
+or nil when the namespace is already connected
+*/
+func (w *connectWaiters) add() chan error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.connected {
+		return nil
+	}
+
+	if w.waiters == nil {
+		w.waiters = make(map[chan error]struct{})
+	}
+
+	wait := make(chan error, 1)
+	w.waiters[wait] = struct{}{}
+	return wait
+}
+
+func (w *connectWaiters) remove(wait chan error) {
+	w.lock.Lock()
+	delete(w.waiters, wait)
+	w.lock.Unlock()
+}
+
+/*
+*
+Tells the waiting calls the outcome of the namespace CONNECT, nil when accepted
+*/
+func (w *connectWaiters) notify(err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.connected = err == nil
+	for wait := range w.waiters {
+		wait <- err
+	}
+	w.waiters = nil
+}
+
+func (w *connectWaiters) setDisconnected() {
+	w.lock.Lock()
+	w.connected = false
+	w.lock.Unlock()
+}
+
+/*
+*
+Connects the namespace and returns once the server accepted it.
+Returns the *ConnectError sent by the server when it refused the namespace,
+the error of the connection when it is lost and not retried,
+ErrorReconnectFailed once the retries are exhausted,
+ctx.Err() when ctx is done, and ErrorConnectTimeout after ConnectTimeout.
+*/
+func (c *Client) ConnectContext(ctx context.Context) error {
+	wait := c.connectWaiters.add()
+	if wait == nil {
+		return nil
+	}
+
+	if err := c.Connect(); err != nil {
+		c.connectWaiters.remove(wait)
+		return err
+	}
+
+	timer := time.NewTimer(c.connectTimeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-wait:
+		return err
+	case <-ctx.Done():
+		c.connectWaiters.remove(wait)
+		return ctx.Err()
+	case <-timer.C:
+		c.connectWaiters.remove(wait)
+		return ErrorConnectTimeout
+	}
+}
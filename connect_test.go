@@ -0,0 +1,149 @@
+package socketio_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/pipe"
+)
+
+func TestConnectContext(t *testing.T) {
+	d := newPipeDialer()
+	c := newTestClient(t, "http://localhost", d.option())
+
+	done := make(chan error, 1)
+	go func() { done <- c.ConnectContext(context.Background()) }()
+
+	srv := d.open(t)
+	if msg := readMessage(t, srv); msg != "40" {
+		t.Fatalf("got %q", msg)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("returned before the CONNECT: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	srv.WriteMessage(`40{"sid":"n"}`)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("not connected")
+	}
+
+	// no race with the handshake
+	if err := c.Emit("hello"); err != nil {
+		t.Fatal(err)
+	}
+	if msg := readMessage(t, srv); msg != `42["hello"]` {
+		t.Fatalf("got %q", msg)
+	}
+}
+
+func TestConnectContextDone(t *testing.T) {
+	d := newPipeDialer()
+	c := newTestClient(t, "http://localhost", d.option())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.ConnectContext(ctx) }()
+
+	d.open(t)
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("got %v", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("not returned")
+	}
+}
+
+func TestConnectTimeout(t *testing.T) {
+	d := newPipeDialer()
+	b := &socketio.ClientBuilder{}
+	c := newTestClient(t, "http://localhost", d.option(), b.WithConnectTimeout(50*time.Millisecond))
+
+	done := make(chan error, 1)
+	go func() { done <- c.ConnectContext(context.Background()) }()
+
+	d.open(t)
+	select {
+	case err := <-done:
+		if err != socketio.ErrorConnectTimeout {
+			t.Fatalf("got %v", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("not returned")
+	}
+}
+
+func TestConnectContextTransportError(t *testing.T) {
+	d := newPipeDialer()
+	b := &socketio.ClientBuilder{}
+	c := newTestClient(t, "http://localhost", d.option(), b.WithReconnection(false))
+
+	done := make(chan error, 1)
+	go func() { done <- c.ConnectContext(context.Background()) }()
+
+	// closed before the OPEN packet
+	var srv *pipe.Connection
+	select {
+	case srv = <-d.servers:
+	case <-time.After(testTimeout):
+		t.Fatal("no connection")
+	}
+	srv.Close()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("connected")
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("not returned")
+	}
+}
+
+func TestConnectContextReconnectFailed(t *testing.T) {
+	d := newPipeDialer()
+	var dials atomic.Int32
+	b := &socketio.ClientBuilder{}
+	c := newTestClient(t, "http://localhost", b.WithReconnection(true), b.WithReconnectionAttempts(2),
+		b.WithReconnectionDelay(10*time.Millisecond), b.WithReconnectionDelayMax(20*time.Millisecond),
+		b.WithDialer(func(url string) (socketio.TransportConn, error) {
+			// only the first connection is opened
+			if dials.Add(1) > 1 {
+				return nil, errors.New("refused")
+			}
+			client, server := d.transport.Pipe()
+			d.servers <- server
+			return client, nil
+		}))
+
+	done := make(chan error, 1)
+	go func() { done <- c.ConnectContext(context.Background()) }()
+
+	srv := d.open(t)
+	if msg := readMessage(t, srv); msg != "40" {
+		t.Fatalf("got %q", msg)
+	}
+	srv.Close()
+
+	select {
+	case err := <-done:
+		if err != socketio.ErrorReconnectFailed {
+			t.Fatalf("got %v, want ErrorReconnectFailed", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("not returned")
+	}
+}
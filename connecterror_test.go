@@ -0,0 +1,38 @@
+package socketio_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+)
+
+func TestConnectError(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+	srv.Reject("/", "forbidden", map[string]interface{}{"code": 403})
+
+	c := newTestClient(t, srv.URL)
+	got := make(chan *socketio.ConnectError, 1)
+	c.On(socketio.OnConnectError, func(ch *socketio.Channel, err *socketio.ConnectError) { got <- err })
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	var connectErr *socketio.ConnectError
+	if err := c.ConnectContext(ctx); !errors.As(err, &connectErr) || connectErr.Message != "forbidden" {
+		t.Fatalf("got %v", err)
+	}
+
+	select {
+	case err := <-got:
+		data, _ := err.Data.(map[string]interface{})
+		if err.Message != "forbidden" || data["code"] != float64(403) {
+			t.Fatalf("got %+v", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("no connect_error")
+	}
+}
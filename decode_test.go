@@ -0,0 +1,41 @@
+package socketio_test
+
+import (
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/websocket"
+)
+
+func TestParseError(t *testing.T) {
+	for _, msg := range []string{
+		// an attachment never sent, then a text packet
+		`451-["image",{"_placeholder":true,"num":0}]`,
+		`45999999999-["image"]`,
+		`42["a",`,
+		`4299999999999999999999["a"]`,
+	} {
+		t.Run(msg, func(t *testing.T) {
+			d := newPipeDialer()
+			c := newTestClient(t, "http://localhost", d.option())
+
+			closed := make(chan *websocket.CloseError, 1)
+			c.On(socketio.OnDisconnection, func(ch *socketio.Channel, err *websocket.CloseError) { closed <- err })
+			c.Connect()
+
+			srv := d.accept(t)
+			srv.WriteMessage(msg)
+			srv.WriteMessage(`42["text"]`)
+
+			select {
+			case err := <-closed:
+				if err.Code != socketio.ParseErrorCode {
+					t.Fatalf("got %v", err)
+				}
+			case <-time.After(testTimeout):
+				t.Fatal("not closed")
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package socketio
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/SavvasMohito/go-socket.io-client/parser"
+)
+
+/*
+*
+How the packets of a namespace are handed to its handlers
+*/
+type DispatchMode int
+
+const (
+	// DispatchConcurrent runs the handlers of every packet in its own goroutine
+	DispatchConcurrent DispatchMode = iota
+	// DispatchSerial runs the handlers one packet at a time, in the order received
+	DispatchSerial
+	// DispatchPerEvent runs the packets of an event in the order received,
+	// the packets of different events concurrently
+	DispatchPerEvent
+	// DispatchPerKey runs the packets of a key returned by DispatchKey in the
+	// order received, the packets of different keys concurrently
+	DispatchPerKey
+)
+
+/*
+*
+What to do with an incoming packet when HandlerQueueSize packets are already waiting
+*/
+type OverloadPolicy int
+
+const (
+	// OverloadBlock stops reading the connection until a packet is handled
+	OverloadBlock OverloadPolicy = iota
+	// OverloadDrop discards the packet
+	OverloadDrop
+	// OverloadDisconnect discards the packet and closes the connection
+	OverloadDisconnect
+)
+
+const (
+	// lane of the packets other than events, ps: CONNECT and DISCONNECT
+	controlLane = "\x00control"
+	serialLane  = "\x00serial"
+)
+
+/*
+*
+Passed to the handlers of OnOverload, the handlers are not keeping up
+with the packets of the server
+*/
+type OverloadError struct {
+	// event of the packet
+	Event  string
+	Queued int
+	Policy OverloadPolicy
+}
+
+func (e *OverloadError) Error() string {
+	return fmt.Sprintf("socket.io: %d packets waiting for a handler", e.Queued)
+}
+
+/*
+*
+Packets waiting for the previous packets of their lane
+*/
+type dispatchLane struct {
+	tasks []dispatchTask
+}
+
+/*
+*
+Handling of a packet, only the events wait for a worker and count in
+the queue, the other packets would be lost or delayed behind them
+*/
+type dispatchTask struct {
+	run   func()
+	event bool
+}
+
+/*
+*
+Runs the handlers of the packets of a namespace in the order of their lane,
+the events on at most maxWorkers goroutines, a worker exits once there is nothing to run
+*/
+type dispatcher struct {
+	mode DispatchMode
+	key  func(event string, args ...interface{}) string
+
+	// 0 is unbounded
+	maxWorkers int
+	queueSize  int
+	overload   OverloadPolicy
+
+	lanes map[string]*dispatchLane
+	// jobs waiting for a worker, ps: an event or the next event of a lane,
+	// every job stands for one pending event
+	ready   []dispatchTask
+	running int
+	// events received and not given to a worker yet
+	pending int
+	// signaled when a packet is handled, the blocked reader waits for it
+	cond *sync.Cond
+	lock sync.Mutex
+}
+
+/*
+*
+Lane of a packet, the empty string when it runs on its own
+*/
+func (d *dispatcher) laneOf(packet *parser.Packet) string {
+	switch d.mode {
+	case DispatchSerial:
+		return serialLane
+	case DispatchPerEvent, DispatchPerKey:
+	default:
+		return ""
+	}
+
+	if packet.Type != parser.EVENT && packet.Type != parser.BINARY_EVENT {
+		return controlLane
+	}
+
+	event := packetEvent(packet)
+	if d.mode == DispatchPerKey && d.key != nil {
+		var args []interface{}
+		// ps: 42[] has no event
+		if data, _ := packet.Data.([]interface{}); len(data) > 0 {
+			args = data[1:]
+		}
+		return "k" + d.key(event, args...)
+	}
+	return "e" + event
+}
+
+/*
+*
+Schedules run, the handling of packet, returns the overload when the
+packet is discarded by the overload policy. overloaded is called with
+the lock held as soon as an event finds the queue full, before blocking.
+*/
+func (d *dispatcher) dispatch(packet *parser.Packet, run func(), overloaded func(overload *OverloadError)) *OverloadError {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	event := packet.Type == parser.EVENT || packet.Type == parser.BINARY_EVENT
+	if event && d.queueSize > 0 && d.pending >= d.queueSize {
+		overload := &OverloadError{
+			Event:  packetEvent(packet),
+			Queued: d.pending,
+			Policy: d.overload,
+		}
+		overloaded(overload)
+		if d.overload != OverloadBlock {
+			return overload
+		}
+
+		if d.cond == nil {
+			d.cond = sync.NewCond(&d.lock)
+		}
+		for d.pending >= d.queueSize {
+			d.cond.Wait()
+		}
+	}
+	if event {
+		d.pending++
+	}
+
+	task := dispatchTask{run: run, event: event}
+	lane := d.laneOf(packet)
+	if lane == "" {
+		d.start(task)
+		return nil
+	}
+
+	if l, ok := d.lanes[lane]; ok {
+		l.tasks = append(l.tasks, task)
+		return nil
+	}
+
+	if d.lanes == nil {
+		d.lanes = make(map[string]*dispatchLane)
+	}
+	l := &dispatchLane{}
+	d.lanes[lane] = l
+	d.start(dispatchTask{
+		run: func() {
+			d.runLane(lane, l, task)
+		},
+		event: event,
+	})
+
+	return nil
+}
+
+/*
+*
+Runs task on a new worker, or once a worker is free
+for an event, the lock is held
+*/
+func (d *dispatcher) start(task dispatchTask) {
+	if task.event && d.maxWorkers > 0 && d.running >= d.maxWorkers {
+		d.ready = append(d.ready, task)
+		return
+	}
+
+	d.running++
+	d.taken(task)
+	go d.work(task.run)
+}
+
+func (d *dispatcher) work(job func()) {
+	for {
+		job()
+
+		d.lock.Lock()
+		if len(d.ready) == 0 {
+			d.running--
+			d.lock.Unlock()
+			return
+		}
+		task := d.ready[0]
+		d.ready[0] = dispatchTask{}
+		d.ready = d.ready[1:]
+		d.taken(task)
+		d.lock.Unlock()
+
+		job = task.run
+	}
+}
+
+/*
+*
+Runs the packets of a lane until it is empty, the lane is then removed.
+The worker is handed over when other jobs are waiting for one.
+*/
+func (d *dispatcher) runLane(lane string, l *dispatchLane, task dispatchTask) {
+	for {
+		task.run()
+
+		d.lock.Lock()
+		if len(l.tasks) == 0 {
+			delete(d.lanes, lane)
+			d.lock.Unlock()
+			return
+		}
+		task = l.tasks[0]
+		l.tasks[0] = dispatchTask{}
+		l.tasks = l.tasks[1:]
+
+		if task.event && len(d.ready) > 0 {
+			next := task
+			d.ready = append(d.ready, dispatchTask{
+				run: func() {
+					d.runLane(lane, l, next)
+				},
+				event: true,
+			})
+			d.lock.Unlock()
+			return
+		}
+		d.taken(task)
+		d.lock.Unlock()
+	}
+}
+
+/*
+*
+The packet of task is given to a worker, an event leaves the queue, the lock is held
+*/
+func (d *dispatcher) taken(task dispatchTask) {
+	if !task.event {
+		return
+	}
+
+	d.pending--
+	if d.cond != nil {
+		d.cond.Broadcast()
+	}
+}
+
+func packetEvent(packet *parser.Packet) string {
+	if packet.Type != parser.EVENT && packet.Type != parser.BINARY_EVENT {
+		return ""
+	}
+
+	data, _ := packet.Data.([]interface{})
+	if len(data) == 0 {
+		return ""
+	}
+	event, _ := data[0].(string)
+	return event
+}
@@ -0,0 +1,120 @@
+package socketio_test
+
+import (
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/parser"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+)
+
+func bookKey(event string, args ...interface{}) string {
+	if len(args) == 0 {
+		return ""
+	}
+	book, _ := args[0].(map[string]interface{})
+	key, _ := book["book"].(string)
+	return key
+}
+
+func TestDispatchModes(t *testing.T) {
+	for _, mode := range []socketio.DispatchMode{socketio.DispatchSerial, socketio.DispatchPerEvent, socketio.DispatchPerKey} {
+		srv := sockettest.NewServer()
+		defer srv.Close()
+
+		b := &socketio.ClientBuilder{}
+		c := newTestClient(t, srv.URL, b.WithDispatchMode(mode), b.WithDispatchKey(bookKey))
+
+		var lock sync.Mutex
+		got := map[string][]int{}
+		var wg sync.WaitGroup
+		const n = 100
+		wg.Add(2 * n)
+		c.On("update", func(ch *socketio.Channel, u struct {
+			Book string `json:"book"`
+			Seq  int    `json:"seq"`
+		}) {
+			time.Sleep(time.Duration(rand.Intn(300)) * time.Microsecond)
+			lock.Lock()
+			got[u.Book] = append(got[u.Book], u.Seq)
+			lock.Unlock()
+			wg.Done()
+		})
+
+		connectTestClient(t, c)
+		conn, err := srv.Conn(testTimeout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < n; i++ {
+			conn.Emit("/", "update", map[string]interface{}{"book": "a", "seq": i})
+			conn.Emit("/", "update", map[string]interface{}{"book": "b", "seq": i})
+		}
+		wg.Wait()
+
+		// in order within a lane
+		for book, seqs := range got {
+			for i, seq := range seqs {
+				if seq != i {
+					t.Fatalf("mode %d, book %s: got %v", mode, book, seqs)
+				}
+			}
+		}
+	}
+}
+
+func TestDispatchEmptyEvent(t *testing.T) {
+	d := newPipeDialer()
+	b := &socketio.ClientBuilder{}
+	c := newTestClient(t, "http://localhost", d.option(),
+		b.WithDispatchMode(socketio.DispatchPerKey), b.WithDispatchKey(bookKey))
+
+	got := make(chan struct{}, 1)
+	c.On("update", func(ch *socketio.Channel) { got <- struct{}{} })
+	c.Connect()
+
+	srv := d.accept(t)
+	srv.WriteMessage(`42[]`)
+	srv.WriteMessage(`42["update",{"book":"a"}]`)
+	waitSignal(t, got, "update")
+}
+
+func TestDispatchSerialAck(t *testing.T) {
+	d := newPipeDialer()
+	b := &socketio.ClientBuilder{}
+	c := newTestClient(t, "http://localhost", d.option(), b.WithDispatchMode(socketio.DispatchSerial))
+
+	// the handler awaits an ack answered while it runs
+	answers := make(chan interface{}, 1)
+	c.On("ask", func(ch *socketio.Channel) {
+		res, err := ch.Ack("q", testTimeout)
+		if err != nil {
+			answers <- err
+			return
+		}
+		answers <- res
+	})
+	c.Connect()
+
+	srv := d.accept(t)
+	srv.WriteMessage(`42["ask"]`)
+	p, err := parser.DecodeString(readMessage(t, srv)[1:])
+	if err != nil || !p.NeedAck {
+		t.Fatal(p, err)
+	}
+	srv.WriteMessage("43" + strconv.Itoa(p.Id) + `["r"]`)
+
+	select {
+	case res := <-answers:
+		args, ok := res.([]interface{})
+		if !ok || len(args) != 1 || string(args[0].([]byte)) != `"r"` {
+			t.Fatalf("got %v", res)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("deadlock")
+	}
+}
@@ -3,16 +3,12 @@ module github.com/SavvasMohito/go-socket.io-client
 go 1.22.3
 
 require (
-	github.com/buger/jsonparser v1.1.1 // indirect
-	github.com/gofrs/uuid v4.4.0+incompatible // indirect
-	github.com/golang/protobuf v1.5.2 // indirect
-	github.com/gorilla/websocket v1.5.3 // indirect
-	github.com/json-iterator/go v1.1.12 // indirect
-	github.com/mark0725/go-socket.io-client v0.0.0-20240823094316-3768181edb01 // indirect
-	github.com/modern-go/concurrent v0.0.0-20180228061459-e0a39a4cb421 // indirect
-	github.com/modern-go/reflect2 v1.0.2 // indirect
-	github.com/ugorji/go/codec v1.2.12 // indirect
-	github.com/vmihailenco/msgpack v4.0.4+incompatible // indirect
-	google.golang.org/appengine v1.6.8 // indirect
-	google.golang.org/protobuf v1.26.0 // indirect
+	github.com/buger/jsonparser v1.1.1
+	github.com/gofrs/uuid v4.4.0+incompatible
+	github.com/gorilla/websocket v1.5.3
+	github.com/json-iterator/go v1.1.12
+	github.com/modern-go/reflect2 v1.0.2
+	github.com/ugorji/go/codec v1.2.12
 )
+
+require github.com/modern-go/concurrent v0.0.0-20180228061459-e0a39a4cb421 // indirect
@@ -2,13 +2,12 @@ package socketio
 
 import (
 	"reflect"
-	"strconv"
-	"strings"
 	"sync"
 
-	"github.com/buger/jsonparser"
-	"github.com/mark0725/go-socket.io-client/protocol"
-	"github.com/mark0725/go-socket.io-client/utils"
+	"github.com/SavvasMohito/go-socket.io-client/parser"
+	"github.com/SavvasMohito/go-socket.io-client/protocol"
+	"github.com/SavvasMohito/go-socket.io-client/utils"
+	"github.com/SavvasMohito/go-socket.io-client/websocket"
 )
 
 const (
@@ -16,284 +15,314 @@ const (
 	OnConnection    = "connection"
 	OnDisconnection = "disconnection"
 	OnError         = "error"
+	OnConnectError  = "connect_error"
+	OnOverload      = "overload"
+	OnBufferDrop    = "buffer_drop"
+
+	// the outgoing queue reached HighWatermark, and is down to LowWatermark since
+	OnHighWatermark = "high_watermark"
+	OnLowWatermark  = "low_watermark"
+
+	OnReconnectAttempt = "reconnect_attempt"
+	OnReconnect        = "reconnect"
+	OnReconnectError   = "reconnect_error"
+	OnReconnectFailed  = "reconnect_failed"
 )
 
 /*
 *
 System handler function for internal event processing
 */
-type systemHandler func(c *Channel)
+type systemHandler func(c *Channel, args ...interface{})
 
 /*
 *
 Contains maps of message processing functions
 */
 type methods struct {
-	messageHandlers     sync.Map
+	// listeners of every event, in the order they were added
+	messageHandlers     map[string][]*caller
 	messageHandlersLock sync.RWMutex
 
 	onConnection    systemHandler
 	onDisconnection systemHandler
+	// asked on CONNECT_ERROR, returns true when the CONNECT was sent again
+	onConnectError func(c *Channel, err *ConnectError) bool
+
+	anyIncoming anyListeners
 }
 
+/*
+*
+Adds a listener of method, after the listeners already added
+*/
 func (m *methods) On(method string, f interface{}) error {
+	return m.addListener(method, f, false)
+}
+
+/*
+*
+Adds a listener of method removed after its first call
+*/
+func (m *methods) Once(method string, f interface{}) error {
+	return m.addListener(method, f, true)
+}
+
+func (m *methods) addListener(method string, f interface{}, once bool) error {
 	c, err := newCaller(f)
 	if err != nil {
 		return err
 	}
+	c.id = funcId(f)
+	c.once = once
 
-	m.messageHandlers.Store(method, c)
+	m.addCaller(method, c)
 	return nil
 }
 
+func (m *methods) addCaller(method string, c *caller) {
+	m.messageHandlersLock.Lock()
+	defer m.messageHandlersLock.Unlock()
+
+	if m.messageHandlers == nil {
+		m.messageHandlers = make(map[string][]*caller)
+	}
+	m.messageHandlers[method] = append(m.messageHandlers[method], c)
+}
+
 /*
 *
-Find message processing function associated with given method
+Removes the first listener of method added with the func value f
 */
-func (m *methods) findMethod(method string) (*caller, bool) {
-	utils.Debug("[handler]find method", method)
-	if f, ok := m.messageHandlers.Load(method); ok {
-		utils.Debug("[handler]method", method)
-		return f.(*caller), true
-	}
+func (m *methods) Off(method string, f interface{}) {
+	id := funcId(f)
 
-	return nil, false
-}
+	m.messageHandlersLock.Lock()
+	defer m.messageHandlersLock.Unlock()
 
-func (m *methods) callLoopEvent(c *Channel, event string, args ...interface{}) {
-	if m.onConnection != nil && event == OnConnection {
-		m.onConnection(c)
-	}
-	if m.onDisconnection != nil && event == OnDisconnection {
-		m.onDisconnection(c)
+	listeners := m.messageHandlers[method]
+	for i, c := range listeners {
+		if c.id == id {
+			m.setListeners(method, append(listeners[:i:i], listeners[i+1:]...))
+			return
+		}
 	}
+}
 
-	f, ok := m.findMethod(event)
-	if !ok {
+/*
+*
+Removes the listeners of the given methods, of every method when none is given
+*/
+func (m *methods) RemoveAllListeners(methods ...string) {
+	m.messageHandlersLock.Lock()
+	defer m.messageHandlersLock.Unlock()
+
+	if len(methods) == 0 {
+		m.messageHandlers = nil
 		return
 	}
 
-	f.callFunc(c, 0, args...)
+	for _, method := range methods {
+		delete(m.messageHandlers, method)
+	}
 }
 
-func (m *methods) getEventArgs(msg string) (string, []interface{}, error) {
-	rawArr := make([]interface{}, 0, 1)
-	event := ""
-	c := 0
+func (m *methods) ListenerCount(method string) int {
+	m.messageHandlersLock.RLock()
+	defer m.messageHandlersLock.RUnlock()
 
-	_, err := jsonparser.ArrayEach([]byte(msg), func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
-		c++
-		if c == 1 {
-			event = string(value)
-		}
+	return len(m.messageHandlers[method])
+}
 
-		if dataType == jsonparser.String {
-			// string must add "\"\"" ps: "message"
-			marshal, err := utils.Json.Marshal("")
-			if err != nil {
-				return
-			}
-			v := make([]byte, 0, len(value)+2)
-			v = append(v, marshal[0])
-			v = append(v, value...)
-			v = append(v, marshal[1])
+/*
+*
+Find message processing functions associated with given method,
+the listeners added with Once are removed
+*/
+func (m *methods) findMethods(method string) []*caller {
+	utils.Debug("[handler]find method", method)
 
-			value = v
+	m.messageHandlersLock.Lock()
+	defer m.messageHandlersLock.Unlock()
+
+	listeners := m.messageHandlers[method]
+	kept := listeners[:0:0]
+	for _, c := range listeners {
+		if !c.once {
+			kept = append(kept, c)
 		}
+	}
+	if len(kept) != len(listeners) {
+		m.setListeners(method, kept)
+	}
 
-		rawArr = append(rawArr, value)
-	})
+	return listeners
+}
 
-	if err != nil {
-		return "", nil, err
+/*
+*
+Replaces the listeners of method, the slice returned by findMethods
+is never modified in place
+*/
+func (m *methods) setListeners(method string, listeners []*caller) {
+	if len(listeners) == 0 {
+		delete(m.messageHandlers, method)
+		return
 	}
 
-	return event, rawArr, nil
+	m.messageHandlers[method] = listeners
 }
-func (m *methods) processIncomingMessageText(c *Channel, msg string) {
-	mType, err := strconv.Atoi(string(msg[0]))
-	if err != nil {
+
+func (m *methods) callLoopEvent(c *Channel, event string, args ...interface{}) {
+	if m.onConnection != nil && event == OnConnection {
+		m.onConnection(c, args...)
+	}
+	if m.onDisconnection != nil && event == OnDisconnection {
+		m.onDisconnection(c, args...)
+	}
+
+	for _, f := range m.findMethods(event) {
+		_, err := f.call(c, event, args, c.newAck(0, false))
+		m.handlerError(c, event, err)
+	}
+}
+
+/*
+*
+Passes the error of a handler to the OnError listeners,
+the errors of the OnError listeners themselves are only logged
+*/
+func (m *methods) handlerError(c *Channel, event string, err error) {
+	if err == nil {
 		return
 	}
 
-	switch mType {
-	case protocol.CONNECT:
-		sid, err := jsonparser.GetString([]byte(msg[1:]), "sid")
-		if err != nil {
-			return
-		}
+	utils.Debug("[handler]", event, "error:", err)
+	if event != OnError {
+		m.callLoopEvent(c, OnError, err)
+	}
+}
 
-		c.header.Sid = sid
-		m.callLoopEvent(c, OnConnection)
-	case protocol.DISCONNECT:
-		closeChannel(c, m)
-	case protocol.EVENT:
-		utils.Debug("[handler]event msg:", msg)
-		i1 := strings.Index(msg, "[")
-		i2 := strings.Index(msg, "{")
-		di := i1
-		if i2 > -1 && (i2 < i1 || i1 == -1) {
-			di = i2
+/*
+*
+Stores the session of a namespace CONNECT before its packet is dispatched,
+so that the events sent right after it see the pid. Returns false when the
+packet does not connect the namespace.
+*/
+func (m *methods) connected(c *Channel, packet *parser.Packet) bool {
+	fields, _ := packet.Data.(map[string]interface{})
+	sid, _ := fields["sid"].(string)
+	if sid == "" {
+		// in protocol v3 the CONNECT of a namespace has no payload, and the
+		// default namespace is already connected with the OPEN packet
+		if c.getConn().GetProtocol() != protocol.Protocol3 || c.namespace == rootNamespace {
+			return false
 		}
+		sid = c.Id()
+	}
+	pid, _ := fields["pid"].(string)
 
-		i3 := strings.Index(msg, ",")
-		nsp := protocol.DefaultNsp
-		acki := 1
-		if i3 > -1 && i3 < di {
-			nsp = msg[1:i3]
-			acki = i3 + 1
-		}
-		id := ""
-		ackId := -1
-		if acki < di {
-			id = msg[acki:di]
-			ackId, _ = strconv.Atoi(id)
-			utils.Debug("[handler]event ackid:", ackId)
-		}
+	c.setSession(sid, pid)
+	return true
+}
 
-		utils.Debug("[handler]event nsp:", nsp)
-		utils.Debug("[handler]event msg:", msg[di:])
-		event, args, err := m.getEventArgs(msg[di:])
-		if err != nil {
+/*
+*
+Processes a packet of the namespace of c, in text mode its []byte
+attachments are already in place of their placeholders
+*/
+func (m *methods) processPacket(c *Channel, packet *parser.Packet) {
+	switch packet.Type {
+	case parser.CONNECT:
+		// the session is already stored by connected on the read loop
+		m.callLoopEvent(c, OnConnection)
+	case parser.DISCONNECT:
+		closeErr := &websocket.CloseError{}
+		closeErr.Code = ServerDisconnectCode
+		closeErr.Text = ServerDisconnectTxt
+
+		closeChannel(c, m, closeErr)
+	case parser.EVENT, parser.BINARY_EVENT:
+		data, _ := packet.Data.([]interface{})
+		if len(data) == 0 {
 			return
 		}
-
-		f, ok := m.findMethod(event)
+		event, ok := data[0].(string)
 		if !ok {
 			return
 		}
 
-		utils.Debug("[handler]event args: ", args)
-		if len(args) == 1 {
-			f.callFunc(c, 1)
-			return
-		}
-		ackRes := f.callFunc(c, 1, args[1:]...)
-		// ack
-		//if string(msg[1]) != "[" {
-		if id != "" {
-			// ackId, offset, err := parseAckId(msg[1:])
-			// if err != nil || ackId < 0 {
-			// 	return
-			// }
-
-			arr := make([]interface{}, 0, 1)
-			for _, v := range ackRes {
-				arr = append(arr, v.Interface())
+		// with connection state recovery, the offset is sent as last argument
+		if len(data) > 1 {
+			if offset, ok := data[len(data)-1].(string); ok {
+				c.setOffset(offset)
 			}
+		}
 
-			r := &protocol.Message{
-				Type:  protocol.ACK,
-				Nsp:   nsp,
-				AckId: ackId,
-				Args:  arr,
-			}
+		m.anyIncoming.call(c, event, parser.Float64Numbers(data[1:]).([]interface{}))
 
-			c.out <- protocol.GetMsgPacket(r)
-		}
-	case protocol.ACK:
-		ackId, offset, err := parseAckId(msg[1:])
-		if err != nil || ackId < 0 {
+		listeners := m.findMethods(event)
+		if len(listeners) == 0 {
 			return
 		}
 
-		if waiter, err := c.ack.getWaiter(ackId); err == nil {
-			_, args, err := m.getEventArgs(msg[1+offset:])
-			if err != nil {
-				return
+		utils.Debug("[handler]event args: ", data[1:])
+		ack := c.newAck(packet.Id, packet.NeedAck)
+		// the ack is answered with the results of the first listener returning any,
+		// unless a listener takes an Ack argument, or already answered it
+		var ackRes []reflect.Value
+		takesAck := false
+		for _, f := range listeners {
+			res, err := f.call(c, event, data[1:], ack)
+			m.handlerError(c, event, err)
+			if ackRes == nil && len(res) > 0 {
+				ackRes = res
 			}
-			waiter <- args
+			takesAck = takesAck || f.takesAck
 		}
-	case protocol.CONNECT_ERROR:
-		closeChannel(c, m)
-	case protocol.BINARY_EVENT:
-	case protocol.BINARY_ACK:
-	}
-}
-
-func (m *methods) processIncomingMessage(c *Channel, msg string) {
-	if !c.conn.GetUseBinaryMessage() {
-		go m.processIncomingMessageText(c, msg)
-		return
-	}
-
-	packet := &protocol.MsgPack{}
-	err := utils.Json.UnmarshalFromString(msg, &packet)
-	if err != nil {
-		return
-	}
+		// ack
+		if packet.NeedAck && !takesAck {
+			arr := make([]interface{}, 0, 1)
+			for _, v := range ackRes {
+				arr = append(arr, v.Interface())
+			}
 
-	switch packet.Type {
-	case protocol.CONNECT:
-		// server protocol 4 & binary msg -> client protocol 3 // 4{"type":0,"data":null,"nsp":"/","id":0}
-		if packet.Data == nil {
-			return
-		}
-		// {"type":0,"data":{},"nsp":"/","id":0}
-		if reflect.ValueOf(packet.Data).Len() == 0 {
-			return
+			ack(arr...)
 		}
-		if packet.Id < 0 {
+	case parser.ACK, parser.BINARY_ACK:
+		waiter, err := c.ack.getWaiter(packet.Id)
+		if err != nil {
 			return
 		}
 
-		c.header.Sid = reflect.ValueOf(packet.Data).MapIndex(reflect.ValueOf("sid")).Interface().(string)
-		m.callLoopEvent(c, OnConnection)
-	case protocol.DISCONNECT:
-		closeChannel(c, m)
-	case protocol.EVENT:
-		data := packet.Data.([]interface{})
-		if len(data) == 0 {
+		data, _ := packet.Data.([]interface{})
+		// in text mode the results of an ACK are passed as raw json
+		if packet.Type == parser.ACK && !c.BinaryMessage() {
+			args := make([]interface{}, 0, len(data))
+			for _, v := range data {
+				marshal, err := utils.Json.Marshal(v)
+				if err != nil {
+					return
+				}
+				args = append(args, marshal)
+			}
+			waiter.resolve(args, data, nil)
 			return
 		}
-		event := data[0].(string)
 
-		f, ok := m.findMethod(event)
-		if !ok {
-			return
-		}
-		if len(data) == 1 {
-			f.callFunc(c, 0)
+		waiter.resolve(parser.Float64Numbers(data), data, nil)
+	case parser.CONNECT_ERROR:
+		connectErr := parseConnectError(packet.Data)
+		if m.onConnectError != nil && m.onConnectError(c, connectErr) {
 			return
 		}
 
-		ackRes := f.callFunc(c, 0, data[1:]...)
-		// ack
-		if packet.Id >= 0 {
-
-			arr := make([]interface{}, 0, 1)
-
-			for _, v := range ackRes {
-				arr = append(arr, v.Interface())
-			}
-
-			r := &protocol.Message{
-				Type:  protocol.ACK,
-				Nsp:   packet.Nsp,
-				AckId: packet.Id,
-				Args:  arr,
-			}
+		m.callLoopEvent(c, OnConnectError, connectErr)
 
-			c.out <- protocol.GetMsgPacket(r)
-		}
-	case protocol.ACK:
-		if waiter, err := c.ack.getWaiter(packet.Id); err == nil {
-			waiter <- packet.Data
-		}
-	case protocol.CONNECT_ERROR:
-		closeChannel(c, m)
-	case protocol.BINARY_EVENT:
-	case protocol.BINARY_ACK:
-	}
-}
+		closeErr := &websocket.CloseError{}
+		closeErr.Code = ConnectErrorCode
+		closeErr.Text = ConnectErrorTxt
 
-func parseAckId(msg string) (int, int, error) {
-	var offset = 0
-	var id = ""
-	for msg[offset] >= 48 && msg[offset] <= 57 {
-		id = id + string(msg[offset])
-		offset++
+		closeChannel(c, m, closeErr)
 	}
-	ret, err := strconv.Atoi(id)
-	return ret, offset, err
 }
@@ -0,0 +1,95 @@
+package socketio
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+*
+Heartbeat of an engine.io connection, in protocol v4 the server sends a ping
+and the client answers with a pong, in protocol v3 it is the other way round
+*/
+type heartbeat struct {
+	// a ping (v4) or a pong (v3) arrived
+	beat chan struct{}
+	// the connection is closed
+	done     chan struct{}
+	doneOnce sync.Once
+
+	sent time.Time
+	rtt  time.Duration
+	lock sync.Mutex
+}
+
+func newHeartbeat() *heartbeat {
+	return &heartbeat{
+		beat: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+}
+
+/*
+*
+Records a ping sent by the client, in protocol v3
+*/
+func (h *heartbeat) ping() {
+	h.lock.Lock()
+	h.sent = time.Now()
+	h.lock.Unlock()
+}
+
+/*
+*
+Records a pong received by the client, in protocol v3
+*/
+func (h *heartbeat) pong() {
+	h.lock.Lock()
+	if !h.sent.IsZero() {
+		h.rtt = time.Since(h.sent)
+		h.sent = time.Time{}
+	}
+	h.lock.Unlock()
+
+	h.received()
+}
+
+/*
+*
+The server is alive, the watchdog is reset
+*/
+func (h *heartbeat) received() {
+	select {
+	case h.beat <- struct{}{}:
+	default:
+	}
+}
+
+func (h *heartbeat) getRTT() time.Duration {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.rtt
+}
+
+func (h *heartbeat) stop() {
+	h.doneOnce.Do(func() {
+		close(h.done)
+	})
+}
+
+/*
+*
+Heartbeat values of the OPEN packet, the transport defaults when missing
+*/
+func pingParams(conn TransportConn, header Header) (interval, timeout time.Duration) {
+	interval, timeout = conn.PingParams()
+	if header.PingInterval > 0 {
+		interval = time.Duration(header.PingInterval) * time.Millisecond
+	}
+	if header.PingTimeout > 0 {
+		timeout = time.Duration(header.PingTimeout) * time.Millisecond
+	}
+
+	return interval, timeout
+}
@@ -0,0 +1,73 @@
+package socketio_test
+
+import (
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/protocol"
+	"github.com/SavvasMohito/go-socket.io-client/websocket"
+)
+
+func TestHeartbeat(t *testing.T) {
+	d := newPipeDialer()
+	d.handshake = `0{"sid":"s","upgrades":[],"pingInterval":50,"pingTimeout":50}`
+	c := newTestClient(t, "http://localhost", d.option())
+
+	closed := make(chan *websocket.CloseError, 1)
+	c.On(socketio.OnDisconnection, func(ch *socketio.Channel, err *websocket.CloseError) { closed <- err })
+	c.Connect()
+	srv := d.accept(t)
+
+	// the pings of the server keep the connection open, and are answered
+	for i := 0; i < 4; i++ {
+		time.Sleep(50 * time.Millisecond)
+		srv.WriteMessage("2")
+		if msg := readMessage(t, srv); msg != "3" {
+			t.Fatalf("got %q, want a pong", msg)
+		}
+	}
+	select {
+	case err := <-closed:
+		t.Fatalf("closed: %v", err)
+	default:
+	}
+
+	if rtt := c.Manager().RTT(); rtt != 0 {
+		t.Fatalf("got RTT %v in protocol v4", rtt)
+	}
+
+	// the server went quiet
+	select {
+	case err := <-closed:
+		if err.Code != socketio.PingTimeoutCode {
+			t.Fatalf("got %v", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("no ping timeout")
+	}
+}
+
+func TestHeartbeatV3(t *testing.T) {
+	d := newPipeDialer()
+	d.transport.Protocol = protocol.Protocol3
+	d.handshake = `0{"sid":"s","upgrades":[],"pingInterval":50,"pingTimeout":50}`
+	c := newTestClient(t, "http://localhost", d.option())
+	c.Connect()
+
+	// the client sends the pings in protocol v3
+	srv := d.open(t)
+	if msg := readMessage(t, srv); msg != "2" {
+		t.Fatalf("got %q, want a ping", msg)
+	}
+	time.Sleep(10 * time.Millisecond)
+	srv.WriteMessage("3")
+
+	deadline := time.Now().Add(testTimeout)
+	for c.Manager().RTT() < 10*time.Millisecond {
+		if time.Now().After(deadline) {
+			t.Fatalf("got RTT %v", c.Manager().RTT())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,139 @@
+package socketio_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/pipe"
+)
+
+const testTimeout = 2 * time.Second
+
+/*
+*
+Builds a client of url, it is closed at the end of the test
+*/
+func newTestClient(t *testing.T, url string, opts ...socketio.ClientOption) *socketio.Client {
+	t.Helper()
+
+	b := &socketio.ClientBuilder{}
+	c, err := b.Build(url, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+
+	return c
+}
+
+func connectTestClient(t *testing.T, c *socketio.Client) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	if err := c.ConnectContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+/*
+*
+Dials in-memory connections, the server ends are received from servers
+*/
+type pipeDialer struct {
+	transport *pipe.Transport
+	servers   chan *pipe.Connection
+	// OPEN packet written by open
+	handshake string
+}
+
+func newPipeDialer() *pipeDialer {
+	return &pipeDialer{
+		transport: pipe.GetDefaultPipeTransport(),
+		servers:   make(chan *pipe.Connection, 4),
+		handshake: `0{"sid":"s","upgrades":[],"pingInterval":25000,"pingTimeout":20000}`,
+	}
+}
+
+func (d *pipeDialer) option() socketio.ClientOption {
+	b := &socketio.ClientBuilder{}
+	return b.WithDialer(func(url string) (socketio.TransportConn, error) {
+		client, server := d.transport.Pipe()
+		d.servers <- server
+		return client, nil
+	})
+}
+
+/*
+*
+Waits for the next connection and opens it, the namespaces are left to the caller
+*/
+func (d *pipeDialer) open(t *testing.T) *pipe.Connection {
+	t.Helper()
+
+	var srv *pipe.Connection
+	select {
+	case srv = <-d.servers:
+	case <-time.After(testTimeout):
+		t.Fatal("no connection")
+	}
+
+	srv.WriteMessage(d.handshake)
+	return srv
+}
+
+/*
+*
+Waits for the next connection and accepts the default namespace on it
+*/
+func (d *pipeDialer) accept(t *testing.T) *pipe.Connection {
+	t.Helper()
+
+	srv := d.open(t)
+	if msg := readMessage(t, srv); msg != "40" {
+		t.Fatalf("got %q, want the CONNECT", msg)
+	}
+	srv.WriteMessage(`40{"sid":"n"}`)
+
+	return srv
+}
+
+func readMessage(t *testing.T, srv *pipe.Connection) string {
+	t.Helper()
+
+	msg := make(chan string, 1)
+	go func() {
+		m, _ := srv.GetMessage()
+		msg <- m
+	}()
+
+	select {
+	case m := <-msg:
+		return m
+	case <-time.After(testTimeout):
+		t.Fatal("no message")
+		return ""
+	}
+}
+
+func waitSignal(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+
+	select {
+	case <-ch:
+	case <-time.After(testTimeout):
+		t.Fatal("no " + what)
+	}
+}
+
+func isDone(f *socketio.AckFuture) bool {
+	select {
+	case <-f.Done():
+		return true
+	default:
+		return false
+	}
+}
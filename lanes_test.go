@@ -0,0 +1,89 @@
+package socketio_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/parser"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+)
+
+func TestCloseAfterData(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL)
+	connectTestClient(t, c)
+	conn, err := srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 50
+	for i := 0; i < n; i++ {
+		if err := c.Emit("e", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Close()
+
+	if _, err := conn.WaitPacket(testTimeout, func(p *parser.Packet) bool {
+		return p.Type == parser.DISCONNECT
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the events queued before Close are sent before the DISCONNECT
+	packets := conn.Packets()
+	last := packets[len(packets)-1]
+	if last.Type != parser.DISCONNECT {
+		t.Fatalf("got %v last", last)
+	}
+	events := 0
+	for _, p := range packets {
+		if p.Type == parser.EVENT {
+			if seq := p.Data.([]interface{})[1]; seq != float64(events) {
+				t.Fatalf("got event %v, want %d", seq, events)
+			}
+			events++
+		}
+	}
+	if events != n {
+		t.Fatalf("got %d events, want %d", events, n)
+	}
+}
+
+func TestControlLane(t *testing.T) {
+	d := newPipeDialer()
+	d.transport.BufferSize = 1
+	b := &socketio.ClientBuilder{}
+	c := newTestClient(t, "http://localhost", d.option(), b.WithDataQueueSize(2000))
+
+	connected := make(chan struct{}, 1)
+	c.On(socketio.OnConnection, func(ch *socketio.Channel) { connected <- struct{}{} })
+	c.Connect()
+	srv := d.accept(t)
+	waitSignal(t, connected, "connection")
+
+	big := strings.Repeat("x", 1000)
+	for i := 0; i < 500; i++ {
+		if err := c.Emit("bulk", big); err != nil {
+			t.Fatal(i, err)
+		}
+	}
+
+	// the pong does not wait behind the queued events
+	srv.WriteMessage("2")
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; ; i++ {
+		msg := readMessage(t, srv)
+		if msg == "3" {
+			if i > 3 {
+				t.Fatalf("pong after %d events", i)
+			}
+			return
+		}
+	}
+}
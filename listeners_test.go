@@ -0,0 +1,68 @@
+package socketio_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+)
+
+func TestListeners(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL)
+
+	var lock sync.Mutex
+	var got []string
+	rec := func(tag string) func(ch *socketio.Channel, s string) string {
+		return func(ch *socketio.Channel, s string) string {
+			lock.Lock()
+			got = append(got, tag+":"+s)
+			lock.Unlock()
+			return tag
+		}
+	}
+
+	second := rec("b")
+	c.On("e", rec("a"))
+	c.On("e", second)
+	c.Once("e", rec("once"))
+	if n := c.ListenerCount("e"); n != 3 {
+		t.Fatalf("got %d listeners", n)
+	}
+
+	connectTestClient(t, c)
+	conn, err := srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// called in order, the first listener answers the ack
+	res, err := conn.EmitWithAck("/", testTimeout, "e", "1")
+	if err != nil || res[0] != "a" {
+		t.Fatal(res, err)
+	}
+	if n := c.ListenerCount("e"); n != 2 {
+		t.Fatalf("got %d listeners after once", n)
+	}
+
+	c.Off("e", second)
+	if _, err := conn.EmitWithAck("/", testTimeout, "e", "2"); err != nil {
+		t.Fatal(err)
+	}
+
+	c.RemoveAllListeners("e")
+	if n := c.ListenerCount("e"); n != 0 {
+		t.Fatalf("got %d listeners after RemoveAllListeners", n)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	want := []string{"a:1", "b:1", "once:1", "a:2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
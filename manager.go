@@ -0,0 +1,830 @@
+package socketio
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SavvasMohito/go-socket.io-client/parser"
+	"github.com/SavvasMohito/go-socket.io-client/polling"
+	"github.com/SavvasMohito/go-socket.io-client/protocol"
+	"github.com/SavvasMohito/go-socket.io-client/utils"
+	"github.com/SavvasMohito/go-socket.io-client/websocket"
+)
+
+/*
+*
+Engine.IO connection shared by the namespaces of a server
+
+use Socket to get the client of a namespace, incoming packets are routed
+to the client of their namespace and reconnection is handled once for all
+*/
+type Manager struct {
+	url        string
+	path       string
+	transports []string
+	dialer     Dialer
+	headerFunc func() (http.Header, error)
+
+	controlQueueSize int
+	dataQueueSize    int
+	highWatermark    int
+	lowWatermark     int
+
+	reconnection         bool
+	reconnectionAttempts int
+	backoff              backoff
+
+	conn      TransportConn
+	out       *outQueue
+	header    Header
+	heartbeat *heartbeat
+	alive     bool
+	// OPEN packet received, the namespaces can be connected
+	opened  bool
+	opening bool
+	// closed is set when the last namespace is closed,
+	// it stops reconnection until the next Connect
+	closed       bool
+	reconnecting bool
+	stop         chan struct{}
+
+	sockets map[string]*Client
+	lock    sync.Mutex
+}
+
+func NewManager(addr string, opts *ClientOptions) (*Manager, error) {
+	m := &Manager{
+		sockets: make(map[string]*Client),
+	}
+
+	var err error
+	if addr == "" {
+		return nil, errors.New("EmptyAddrErr")
+	}
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	}
+
+	u.Path = "/socket.io"
+	if opts.Path != "" {
+		u.Path = opts.Path
+	}
+
+	u.Path = u.EscapedPath()
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	m.path = u.Path
+
+	m.url = u.String()
+	utils.Debug("url:", m.url)
+
+	m.transports = []string{TransportWebsocket}
+	if len(opts.Transports) > 0 {
+		for _, t := range opts.Transports {
+			if t != TransportPolling && t != TransportWebsocket {
+				return nil, ErrorUnknownTransport
+			}
+		}
+		m.transports = opts.Transports
+	}
+
+	m.controlQueueSize = controlQueueBufferSize
+	if opts.ControlQueueSize > 0 {
+		m.controlQueueSize = opts.ControlQueueSize
+	}
+	m.dataQueueSize = queueBufferSize
+	if opts.DataQueueSize > 0 {
+		m.dataQueueSize = opts.DataQueueSize
+	}
+
+	m.highWatermark = opts.HighWatermark
+	m.lowWatermark = opts.LowWatermark
+	if m.lowWatermark <= 0 || m.lowWatermark >= m.highWatermark {
+		m.lowWatermark = m.highWatermark / 2
+	}
+
+	m.dialer = opts.Dialer
+	m.headerFunc = opts.HeaderFunc
+
+	m.reconnection = opts.Reconnection
+	m.reconnectionAttempts = opts.ReconnectionAttempts
+	m.backoff = backoff{
+		min:    defaultReconnectionDelay,
+		max:    defaultReconnectionDelayMax,
+		jitter: opts.RandomizationFactor,
+	}
+	if opts.ReconnectionDelay > 0 {
+		m.backoff.min = opts.ReconnectionDelay
+	}
+	if opts.ReconnectionDelayMax > 0 {
+		m.backoff.max = opts.ReconnectionDelayMax
+	}
+
+	return m, nil
+}
+
+/*
+*
+Returns the client of namespace, it is created on first use with
+the namespace options of opts: Auth and the send buffer
+*/
+func (m *Manager) Socket(namespace string, opts *ClientOptions) *Client {
+	namespace = fmtNS(namespace)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if c, ok := m.sockets[namespace]; ok {
+		return c
+	}
+
+	if opts == nil {
+		opts = &ClientOptions{}
+	}
+
+	c := newClient(m, namespace, opts)
+	m.sockets[namespace] = c
+	return c
+}
+
+/*
+*
+Returns the round-trip time of the last ping, measured in protocol v3 only
+since in protocol v4 the server sends the pings, it is always 0 then
+*/
+func (m *Manager) RTT() time.Duration {
+	m.lock.Lock()
+	hb := m.heartbeat
+	m.lock.Unlock()
+
+	if hb == nil {
+		return 0
+	}
+	return hb.getRTT()
+}
+
+/*
+*
+Closes every namespace, and the engine.io connection with the last one
+*/
+func (m *Manager) Close() {
+	m.lock.Lock()
+	sockets := make([]*Client, 0, len(m.sockets))
+	for _, c := range m.sockets {
+		sockets = append(sockets, c)
+	}
+	m.lock.Unlock()
+
+	for _, c := range sockets {
+		c.Close()
+	}
+}
+
+/*
+*
+Connects the namespace of c, the engine.io connection is opened first if needed
+*/
+func (m *Manager) connect(c *Client) error {
+	m.lock.Lock()
+	c.active = true
+	if m.stop == nil || m.closed {
+		m.closed = false
+		m.stop = make(chan struct{})
+	}
+
+	// the namespace is connected once the pending connection is open
+	if m.opening || m.reconnecting {
+		m.lock.Unlock()
+		return nil
+	}
+
+	if m.alive {
+		c.channel.initChannel(m.conn, m.out)
+		opened := m.opened
+		m.lock.Unlock()
+
+		if opened {
+			c.sendConnect()
+		}
+		return nil
+	}
+
+	m.opening = true
+	m.lock.Unlock()
+
+	err := m.open()
+
+	m.lock.Lock()
+	m.opening = false
+	m.lock.Unlock()
+
+	return err
+}
+
+/*
+*
+Called when a namespace is closed, the engine.io connection is closed with the last one
+*/
+func (m *Manager) destroy(c *Client) {
+	m.lock.Lock()
+	c.active = false
+	for _, s := range m.sockets {
+		if s.active {
+			m.lock.Unlock()
+			return
+		}
+	}
+
+	if !m.closed {
+		m.closed = true
+		if m.stop != nil {
+			close(m.stop)
+		}
+	}
+
+	alive := m.alive
+	out := m.out
+	m.alive = false
+	m.opened = false
+	if m.heartbeat != nil {
+		m.heartbeat.stop()
+	}
+	m.lock.Unlock()
+
+	// queued behind the events and the DISCONNECT, the write loop
+	// sends them first and then closes the connection
+	if alive {
+		select {
+		case out.data <- protocol.CloseMsg:
+		case <-out.done:
+		}
+	}
+}
+
+func (m *Manager) activeSockets() []*Client {
+	sockets := make([]*Client, 0, len(m.sockets))
+	for _, c := range m.sockets {
+		if c.active {
+			sockets = append(sockets, c)
+		}
+	}
+
+	return sockets
+}
+
+/*
+*
+Opens the engine.io connection and starts the read and write loops,
+the namespaces are connected when the OPEN packet arrives
+*/
+func (m *Manager) open() error {
+	var err error
+	tr := websocket.GetDefaultWebsocketTransport()
+
+	// the credentials of the handshake are fetched on every attempt
+	if m.headerFunc != nil {
+		tr.RequestHeader, err = m.headerFunc()
+		if err != nil {
+			return err
+		}
+	}
+
+	u, err := url.Parse(m.url)
+	if err != nil {
+		return err
+	}
+
+	queryParams := u.Query()
+	queryParams.Set("transport", m.transports[0])
+
+	if tr.Protocol == protocol.Protocol3 {
+		queryParams.Set("EIO", "3")
+	} else if tr.Protocol == protocol.Protocol4 {
+		queryParams.Set("EIO", "4")
+	} else {
+		queryParams.Set("EIO", "4")
+	}
+
+	queryParams.Set("t", time.Now().Format("02150405"))
+
+	u.RawQuery = queryParams.Encode()
+
+	eioAddr := u.String()
+	utils.Debug("[sockio-client] full addr: ", eioAddr)
+
+	conn, err := m.dial(eioAddr, tr)
+	if err != nil {
+		return err
+	}
+
+	out := newOutQueue(m.controlQueueSize, m.dataQueueSize)
+	out.high = m.highWatermark
+	out.low = m.lowWatermark
+	// called in order, the low watermark must not overtake the high one
+	marks := &loopEvents{call: func(event string) {
+		m.callLoopEvent(event)
+	}}
+	out.onHigh = func() {
+		marks.push(OnHighWatermark)
+	}
+	out.onLow = func() {
+		marks.push(OnLowWatermark)
+	}
+
+	m.lock.Lock()
+	// the last namespace was closed while dialing
+	if m.closed {
+		m.lock.Unlock()
+		conn.Close()
+		return nil
+	}
+
+	hb := newHeartbeat()
+	m.conn = conn
+	m.out = out
+	m.heartbeat = hb
+	m.alive = true
+	m.opened = false
+	for _, c := range m.activeSockets() {
+		c.channel.initChannel(conn, out)
+	}
+	m.lock.Unlock()
+
+	go m.read(conn, out, hb)
+	go m.write(conn, out)
+
+	return nil
+}
+
+/*
+*
+Opens the engine.io connection with the preferred transport,
+long-polling probes websocket when the client may upgrade to it
+*/
+func (m *Manager) dial(addr string, tr *websocket.Transport) (TransportConn, error) {
+	if m.dialer != nil {
+		return m.dialer(addr)
+	}
+
+	if m.transports[0] != TransportPolling {
+		conn, err := tr.Connect(addr)
+		if err != nil {
+			return nil, err
+		}
+		return conn, nil
+	}
+
+	pt := polling.GetDefaultPollingTransport()
+	pt.Protocol = tr.Protocol
+	pt.RequestHeader = tr.RequestHeader
+	for _, t := range m.transports[1:] {
+		if t == TransportWebsocket {
+			pt.Upgrade = tr
+		}
+	}
+
+	conn, err := pt.Connect(addr)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+/*
+*
+Reopens the connection with backoff between attempts, until it succeeds,
+runs out of attempts or the last namespace is closed
+*/
+func (m *Manager) reconnect() {
+	m.lock.Lock()
+	if m.closed || m.reconnecting {
+		m.lock.Unlock()
+		return
+	}
+	m.reconnecting = true
+	stop := m.stop
+	m.lock.Unlock()
+
+	defer func() {
+		m.lock.Lock()
+		m.reconnecting = false
+		m.lock.Unlock()
+	}()
+
+	for {
+		attempt := m.backoff.attempts + 1
+		if m.reconnectionAttempts > 0 && attempt > m.reconnectionAttempts {
+			m.backoff.reset()
+			m.reconnectFailed()
+			m.callLoopEvent(OnReconnectFailed)
+			return
+		}
+
+		delay := m.backoff.duration()
+		utils.Debug("[reconnect] attempt", attempt, "in", delay)
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+
+		m.callLoopEvent(OnReconnectAttempt, attempt)
+
+		if err := m.open(); err != nil {
+			m.callLoopEvent(OnReconnectError, err)
+			continue
+		}
+
+		m.backoff.reset()
+		m.callLoopEvent(OnReconnect, attempt)
+		return
+	}
+}
+
+/*
+*
+Calls event on every connecting namespace
+*/
+func (m *Manager) callLoopEvent(event string, args ...interface{}) {
+	m.lock.Lock()
+	sockets := m.activeSockets()
+	m.lock.Unlock()
+
+	for _, c := range sockets {
+		c.handlers.callLoopEvent(&c.channel, event, args...)
+	}
+}
+
+/*
+*
+Loop events called one at a time off the caller goroutine,
+in the order they are pushed
+*/
+type loopEvents struct {
+	call    func(event string)
+	events  []string
+	running bool
+	lock    sync.Mutex
+}
+
+func (q *loopEvents) push(event string) {
+	q.lock.Lock()
+	q.events = append(q.events, event)
+	if q.running {
+		q.lock.Unlock()
+		return
+	}
+	q.running = true
+	q.lock.Unlock()
+
+	go q.run()
+}
+
+func (q *loopEvents) run() {
+	for {
+		q.lock.Lock()
+		if len(q.events) == 0 {
+			q.running = false
+			q.lock.Unlock()
+			return
+		}
+		event := q.events[0]
+		q.events = q.events[1:]
+		q.lock.Unlock()
+
+		q.call(event)
+	}
+}
+
+/*
+*
+Fails the acks kept for the reconnection and the ConnectContext calls
+waiting for it, it is not coming
+*/
+func (m *Manager) reconnectFailed() {
+	m.lock.Lock()
+	sockets := m.activeSockets()
+	m.lock.Unlock()
+
+	for _, c := range sockets {
+		c.channel.ack.disconnected(false)
+		c.connectWaiters.notify(ErrorReconnectFailed)
+	}
+}
+
+/*
+*
+Closes the engine.io connection on behalf of a read or write loop, unless
+the loop belongs to a connection that has already been replaced
+*/
+func (m *Manager) closeConn(conn TransportConn, args ...interface{}) error {
+	m.lock.Lock()
+	if conn != m.conn || !m.alive {
+		m.lock.Unlock()
+		return nil
+	}
+
+	m.alive = false
+	m.opened = false
+	m.heartbeat.stop()
+	out := m.out
+	sockets := m.activeSockets()
+	reconnect := m.reconnection && !m.closed
+	m.lock.Unlock()
+
+	conn.Close()
+	// the connection is already closed, the write loop stops right away
+	select {
+	case out.control <- protocol.CloseMsg:
+	case <-out.done:
+	}
+
+	for _, c := range sockets {
+		closeChannel(&c.channel, &c.handlers, args...)
+	}
+
+	if reconnect && len(sockets) > 0 {
+		go m.reconnect()
+	}
+
+	return nil
+}
+
+/*
+*
+Stores the engine.io header and connects the namespaces
+*/
+func (m *Manager) onOpen(conn TransportConn, out *outQueue, hb *heartbeat, header Header) {
+	m.lock.Lock()
+	if conn != m.conn {
+		m.lock.Unlock()
+		return
+	}
+	m.header = header
+	m.opened = true
+	sockets := m.activeSockets()
+	m.lock.Unlock()
+
+	go m.schedulePing(conn, out, hb, header)
+
+	for _, c := range sockets {
+		c.channel.setHeader(header)
+		c.sendConnect()
+	}
+}
+
+/*
+*
+Routes a packet to the client of its namespace
+*/
+func (m *Manager) dispatch(packet *parser.Packet) {
+	nsp := fmtNS(packet.Nsp)
+
+	m.lock.Lock()
+	c, ok := m.sockets[nsp]
+	if ok && !c.active {
+		ok = false
+	}
+	m.lock.Unlock()
+
+	if !ok {
+		utils.Debug("[manager] no client for namespace", nsp)
+		return
+	}
+
+	if packet.Type == parser.CONNECT && !c.handlers.connected(&c.channel, packet) {
+		return
+	}
+
+	// the answers of EmitWithAck are resolved on the read loop, a handler
+	// awaiting one would otherwise wait for itself on its lane
+	if packet.Type == parser.ACK || packet.Type == parser.BINARY_ACK {
+		c.handlers.processPacket(&c.channel, packet)
+		return
+	}
+
+	run := func() {
+		c.handlers.processPacket(&c.channel, packet)
+	}
+	// called before the reader blocks with OverloadBlock
+	overloaded := func(overload *OverloadError) {
+		utils.Debug("[manager] overload:", overload)
+		go c.handlers.callLoopEvent(&c.channel, OnOverload, overload)
+	}
+
+	overload := c.dispatcher.dispatch(packet, run, overloaded)
+	if overload != nil && overload.Policy == OverloadDisconnect {
+		m.lock.Lock()
+		conn := m.conn
+		m.lock.Unlock()
+
+		closeErr := &websocket.CloseError{}
+		closeErr.Code = HandlerOverloadCode
+		closeErr.Text = HandlerOverloadTxt
+
+		m.closeConn(conn, closeErr)
+	}
+}
+
+/*
+*
+Decodes a packet of binary mode, ps: {"type":2,"data":["message"],"nsp":"/","id":-1}
+*/
+func (m *Manager) dispatchMsgPack(msg string) {
+	packet := &protocol.MsgPack{Id: -1}
+	if err := utils.Json.UnmarshalFromString(msg, packet); err != nil {
+		utils.Debug("[manager] bad packet:", err)
+		return
+	}
+
+	p := protocol.ParserPacket(packet)
+	m.dispatch(&p)
+}
+
+func (m *Manager) read(conn TransportConn, out *outQueue, hb *heartbeat) error {
+	// in text mode the attachments of a packet follow it as binary messages
+	decoder := parser.NewDecoder(m.dispatch)
+	for {
+		msg, err := conn.GetMessage()
+		if err != nil {
+			return m.closeConn(conn, err)
+		}
+
+		prefix := string(msg[0])
+
+		switch prefix {
+		case protocol.OpenMsg:
+			header := Header{}
+			if err := utils.Json.UnmarshalFromString(msg[1:], &header); err != nil {
+				closeErr := &websocket.CloseError{}
+				closeErr.Code = websocket.ParseOpenMsgCode
+				closeErr.Text = err.Error()
+
+				return m.closeConn(conn, closeErr)
+			}
+
+			m.onOpen(conn, out, hb, header)
+		case protocol.CloseMsg:
+			return m.closeConn(conn)
+		case protocol.PingMsg:
+			// in protocol v4, the server sends a ping, and the client answers with a pong
+			out.control <- protocol.PongMsg
+			hb.received()
+		case protocol.PongMsg:
+			hb.pong()
+		case protocol.UpgradeMsg:
+		case protocol.CommonMsg:
+			// in protocol v3 & binary msg  ps: 4{"type":0,"data":null,"nsp":"/","id":0}
+			// in protocol v3 & text msg  ps: 40 or 41 or 42["message", ...]
+			// in protocol v4 & text msg  ps: 40 or 41 or 42["message", ...]
+			if conn.GetUseBinaryMessage() {
+				m.dispatchMsgPack(msg[1:])
+			} else if err := decoder.Add(msg[1:]); err != nil {
+				return m.parseError(conn, err)
+			}
+		case protocol.BinaryMsg:
+			if err := decoder.Add([]byte(msg[1:])); err != nil {
+				return m.parseError(conn, err)
+			}
+		default:
+			// in protocol v4 & binary msg ps: {"type":0,"data":{"sid":"HWEr440000:1:R1CHyink:shadiao:101"},"nsp":"/","id":0}
+			m.dispatchMsgPack(msg)
+		}
+	}
+}
+
+/*
+*
+The decoder is out of sync with the server once a packet is refused,
+the connection is closed like the reference client does
+*/
+func (m *Manager) parseError(conn TransportConn, err error) error {
+	utils.Debug("[manager] bad packet:", err)
+
+	closeErr := &websocket.CloseError{}
+	closeErr.Code = ParseErrorCode
+	closeErr.Text = ParseErrorTxt + ": " + err.Error()
+
+	return m.closeConn(conn, closeErr)
+}
+
+func (m *Manager) write(conn TransportConn, out *outQueue) error {
+	defer out.close()
+
+	for {
+		// the control packets never wait behind the data packets
+		var msg interface{}
+		select {
+		case msg = <-out.control:
+		default:
+			select {
+			case msg = <-out.control:
+			case msg = <-out.data:
+				out.popped()
+			}
+		}
+
+		if msg == protocol.CloseMsg {
+			conn.Close()
+			return nil
+		}
+
+		err := writePacket(conn, msg)
+		if err != nil {
+			closeErr := &websocket.CloseError{}
+			closeErr.Code = websocket.WriteBufferErrCode
+			closeErr.Text = err.Error()
+
+			m.closeConn(conn, closeErr)
+		}
+	}
+}
+
+/*
+*
+Writes msg to conn, in text mode packets are encoded by the parser and
+those with []byte arguments are followed by their attachments
+*/
+func writePacket(conn TransportConn, msg interface{}) error {
+	packet, ok := msg.(*protocol.MsgPack)
+	if !ok || conn.GetUseBinaryMessage() {
+		return conn.WriteMessage(msg)
+	}
+
+	frames := parser.Encode(protocol.ParserPacket(packet))
+
+	err := conn.WriteMessage(protocol.CommonMsg + string(frames[0]))
+	if err != nil {
+		return err
+	}
+
+	for _, attachment := range frames[1:] {
+		if err := conn.WriteMessage(attachment); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+*
+Watches the heartbeat with the values of the OPEN packet, the connection is
+closed when no ping (v4) or pong (v3) arrives within interval + timeout
+*/
+func (m *Manager) schedulePing(conn TransportConn, out *outQueue, hb *heartbeat, header Header) {
+	interval, timeout := pingParams(conn, header)
+
+	deadline := time.NewTimer(interval + timeout)
+	defer deadline.Stop()
+
+	// in protocol v3, the client sends a ping, and the server answers with a pong
+	var tick <-chan time.Time
+	if conn.GetProtocol() == protocol.Protocol3 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-hb.done:
+			return
+		case <-hb.beat:
+			if !deadline.Stop() {
+				select {
+				case <-deadline.C:
+				default:
+				}
+			}
+			deadline.Reset(interval + timeout)
+		case <-tick:
+			hb.ping()
+			out.control <- protocol.PingMsg
+		case <-deadline.C:
+			utils.Debug("[manager] ping timeout")
+
+			closeErr := &websocket.CloseError{}
+			closeErr.Code = PingTimeoutCode
+			closeErr.Text = PingTimeoutTxt
+
+			m.closeConn(conn, closeErr)
+			return
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package socketio_test
+
+import (
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/parser"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+)
+
+func TestManager(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	b := &socketio.ClientBuilder{}
+	m, err := b.BuildManager(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	root := m.Socket("/", nil)
+	admin := m.Socket("/admin", &socketio.ClientOptions{Auth: map[string]string{"token": "x"}})
+
+	events := make(chan string, 4)
+	root.On("hello", func(ch *socketio.Channel, s string) { events <- "root:" + s })
+	admin.On("hello", func(ch *socketio.Channel, s string) { events <- "admin:" + s })
+	adminClosed := make(chan struct{}, 1)
+	admin.On(socketio.OnDisconnection, func(ch *socketio.Channel, err interface{}) { adminClosed <- struct{}{} })
+
+	connectTestClient(t, root)
+	connectTestClient(t, admin)
+
+	// one connection for both namespaces
+	conn, err := srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.Conn(100 * time.Millisecond); err == nil {
+		t.Fatal("a connection per namespace")
+	}
+
+	connect, err := conn.WaitConnect(testTimeout, "/admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth, _ := connect.Data.(map[string]interface{}); auth["token"] != "x" {
+		t.Fatalf("got auth %v", connect.Data)
+	}
+
+	conn.Emit("/admin", "hello", "admin")
+	conn.Emit("/", "hello", "root")
+	got := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case e := <-events:
+			got[e] = true
+		case <-time.After(testTimeout):
+			t.Fatal("missing events", got)
+		}
+	}
+	if !got["root:root"] || !got["admin:admin"] {
+		t.Fatal(got)
+	}
+
+	// the other namespaces keep the connection
+	conn.Disconnect("/admin")
+	waitSignal(t, adminClosed, "admin disconnection")
+	if err := root.Emit("still", "alive"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.WaitEvent(testTimeout, "/", "still"); err != nil {
+		t.Fatal(err)
+	}
+
+	// closing the last namespace closes the connection
+	root.Close()
+	if _, err := conn.WaitPacket(testTimeout, func(p *parser.Packet) bool {
+		return p.Type == parser.DISCONNECT && (p.Nsp == "" || p.Nsp == "/")
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,74 @@
+package socketio_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+)
+
+// above 2^53, not exact as a float64
+const bigInt int64 = 1234567890123456789
+
+func TestLargeIntegers(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL)
+
+	type args struct {
+		v   int64
+		any interface{}
+	}
+	received := make(chan args, 1)
+	c.On("n", func(ch *socketio.Channel, v int64, any interface{}) {
+		received <- args{v, any}
+	})
+	typed := make(chan int64, 1)
+	socketio.On(c, "typed", func(ctx socketio.Context, v int64) error {
+		typed <- v
+		return nil
+	})
+
+	connectTestClient(t, c)
+	conn, err := srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Emit("/", "n", bigInt, bigInt)
+	conn.Emit("/", "typed", bigInt)
+
+	got := <-received
+	if got.v != bigInt {
+		t.Fatalf("got %d, want %d", got.v, bigInt)
+	}
+	// as decoded by encoding/json
+	if got.any != float64(bigInt) {
+		t.Fatalf("got %#v, want a float64", got.any)
+	}
+	if v := <-typed; v != bigInt {
+		t.Fatalf("got %d from the typed handler, want %d", v, bigInt)
+	}
+
+	future := c.EmitWithAck("q")
+	p, err := conn.WaitEvent(testTimeout, "/", "q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Ack("/", p.Id, bigInt)
+
+	result, err := future.Await(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v int64
+	if err := json.Unmarshal(result.([]interface{})[0].([]byte), &v); err != nil || v != bigInt {
+		t.Fatalf("got %d, %v from the raw result, want %d", v, err, bigInt)
+	}
+	if v, err := socketio.AwaitAs[int64](context.Background(), future); err != nil || v != bigInt {
+		t.Fatalf("got %d, %v from AwaitAs, want %d", v, err, bigInt)
+	}
+}
@@ -14,6 +14,10 @@ const (
 	BINARY_ACK
 )
 
+/*
+*
+Socket.IO packet, the numbers of a decoded Data are json.Number, see Float64Numbers
+*/
 type Packet struct {
 	Type        PacketType  `json:"type"`
 	Nsp         string      `json:"nsp,omitempty"`
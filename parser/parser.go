@@ -7,34 +7,53 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/SavvasMohito/go-socket.io-client/utils"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// a packet announcing more attachments is refused, its reconstruction would never end
+const MaxAttachments = 1000
+
+// the numbers of the payload are kept as json.Number, integers above 2^53 stay exact
+var numberJson = jsoniter.Config{
+	EscapeHTML:             true,
+	SortMapKeys:            true,
+	ValidateJsonRawMessage: true,
+	UseNumber:              true,
+}.Froze()
+
+var (
+	ErrorInvalidPacket      = errors.New("invalid packet")
+	ErrorReconstructing     = errors.New("got plaintext data when reconstructing a packet")
+	ErrorTooManyAttachments = errors.New("too many attachments")
 )
 
 func isBinary(obj interface{}) bool {
 	switch obj.(type) {
-	case []byte, string:
-		return false
-	case []*bytes.Buffer, []interface {
-		Bytes() []byte
-		String() string
-	}:
+	case []byte, *bytes.Buffer:
 		return true
 	default:
 		return false
 	}
 }
 
-func hasBinary(obj interface{}) bool {
+/*
+*
+Returns true when obj holds []byte, in []interface{} and map[string]interface{}
+*/
+func HasBinary(obj interface{}) bool {
 	switch v := obj.(type) {
 	case []interface{}:
 		for _, value := range v {
-			if hasBinary(value) {
+			if HasBinary(value) {
 				return true
 			}
 		}
 		return false
 	case map[string]interface{}:
 		for _, value := range v {
-			if hasBinary(value) {
+			if HasBinary(value) {
 				return true
 			}
 		}
@@ -68,6 +87,9 @@ func _deconstructPacket(data interface{}, buffers *[][]byte) interface{} {
 	case []byte:
 		*buffers = append(*buffers, v)
 		return map[string]interface{}{"_placeholder": true, "num": len(*buffers) - 1}
+	case *bytes.Buffer:
+		*buffers = append(*buffers, v.Bytes())
+		return map[string]interface{}{"_placeholder": true, "num": len(*buffers) - 1}
 	default:
 		return data
 	}
@@ -87,8 +109,20 @@ func _reconstructPacket(data interface{}, buffers [][]byte) interface{} {
 		}
 	case map[string]interface{}:
 		if placeholder, ok := v["_placeholder"].(bool); ok && placeholder {
-			if num, ok := v["num"].(int); ok {
-				return buffers[num]
+			// decoded from json the number is a json.Number
+			switch num := v["num"].(type) {
+			case json.Number:
+				if n, err := num.Int64(); err == nil && n >= 0 && n < int64(len(buffers)) {
+					return buffers[n]
+				}
+			case float64:
+				if int(num) >= 0 && int(num) < len(buffers) {
+					return buffers[int(num)]
+				}
+			case int:
+				if num >= 0 && num < len(buffers) {
+					return buffers[num]
+				}
 			}
 		}
 		for key, value := range v {
@@ -98,7 +132,11 @@ func _reconstructPacket(data interface{}, buffers [][]byte) interface{} {
 	return data
 }
 
-func encodeAsString(packet Packet) string {
+/*
+*
+Encodes packet as text, its []byte are not turned into attachments
+*/
+func EncodeString(packet Packet) string {
 	var builder strings.Builder
 
 	builder.WriteString(fmt.Sprintf("%d", packet.Type))
@@ -107,7 +145,7 @@ func encodeAsString(packet Packet) string {
 		builder.WriteString(fmt.Sprintf("%d-", packet.Attachments))
 	}
 
-	if packet.Nsp != "/" {
+	if packet.Nsp != "" && packet.Nsp != "/" {
 		builder.WriteString(fmt.Sprintf("%s,", packet.Nsp))
 	}
 
@@ -116,7 +154,7 @@ func encodeAsString(packet Packet) string {
 	}
 
 	if packet.Data != nil {
-		dataStr, _ := json.Marshal(packet.Data)
+		dataStr, _ := utils.Json.Marshal(packet.Data)
 		builder.WriteString(string(dataStr))
 	}
 
@@ -125,7 +163,7 @@ func encodeAsString(packet Packet) string {
 
 func encodeAsBinary(packet Packet) [][]byte {
 	packet, buffers := deconstructPacket(packet)
-	encoded := encodeAsString(packet)
+	encoded := EncodeString(packet)
 	buffers = append([][]byte{[]byte(encoded)}, buffers...)
 	return buffers
 }
@@ -133,12 +171,12 @@ func encodeAsBinary(packet Packet) [][]byte {
 func Encode(obj Packet) [][]byte {
 
 	if obj.Type == EVENT || obj.Type == ACK {
-		if hasBinary(obj) {
+		if HasBinary(obj.Data) {
 			obj.Type = map[bool]PacketType{true: BINARY_EVENT, false: BINARY_ACK}[obj.Type == EVENT]
 			return encodeAsBinary(obj)
 		}
 	}
-	return [][]byte{[]byte(encodeAsString(obj))}
+	return [][]byte{[]byte(EncodeString(obj))}
 }
 
 type Decoder struct {
@@ -146,6 +184,15 @@ type Decoder struct {
 	emit          func(packet *Packet)
 }
 
+/*
+*
+Returns a decoder calling emit with every packet decoded,
+a packet with attachments once all of them are added
+*/
+func NewDecoder(emit func(packet *Packet)) *Decoder {
+	return &Decoder{emit: emit}
+}
+
 type BinaryReconstructor struct {
 	packet  Packet
 	buffers [][]byte
@@ -162,11 +209,12 @@ func (d *Decoder) Add(data interface{}) error {
 		if err != nil {
 			return err
 		}
-		if packet.Type == BINARY_EVENT || packet.Type == BINARY_ACK {
-			d.reconstructor = &BinaryReconstructor{packet: packet}
-			if packet.Attachments == 0 {
-				d.emit(&packet)
-			}
+		if d.reconstructor != nil {
+			d.reconstructor = nil
+			return ErrorReconstructing
+		}
+		if (packet.Type == BINARY_EVENT || packet.Type == BINARY_ACK) && packet.Attachments > 0 {
+			d.reconstructor = NewBinaryReconstructor(packet)
 		} else {
 			d.emit(&packet)
 		}
@@ -174,7 +222,7 @@ func (d *Decoder) Add(data interface{}) error {
 		if d.reconstructor == nil {
 			return errors.New("got binary data when not reconstructing a packet")
 		}
-		packet := d.reconstructor.takeBinaryData(data)
+		packet := d.reconstructor.TakeBinaryData(data)
 		if packet != nil {
 			d.reconstructor = nil
 			d.emit(packet)
@@ -188,20 +236,31 @@ func (d *Decoder) Add(data interface{}) error {
 }
 
 func DecodeString(str string) (Packet, error) {
+	if str == "" || str[0] < '0' || str[0] > '6' {
+		return Packet{}, ErrorInvalidPacket
+	}
+
 	packet := Packet{Type: PacketType(str[0] - '0')}
-	func() {
+	err := func() error {
 		i := 1
 		if packet.Type == BINARY_EVENT || packet.Type == BINARY_ACK {
 			j := i
 			for j < len(str) && str[j] != '-' {
 				j++
 			}
-			packet.Attachments = int(str[i:j][0] - '0')
+			attachments, err := strconv.Atoi(str[i:j])
+			if err != nil || j >= len(str) || attachments < 0 {
+				return ErrorInvalidPacket
+			}
+			if attachments > MaxAttachments {
+				return ErrorTooManyAttachments
+			}
+			packet.Attachments = attachments
 			i = j + 1
 		}
 
 		if i >= len(str) {
-			return
+			return nil
 		}
 
 		if str[i] == '/' {
@@ -214,7 +273,7 @@ func DecodeString(str string) (Packet, error) {
 		}
 
 		if i >= len(str) {
-			return
+			return nil
 		}
 
 		if str[i] >= '0' && str[i] <= '9' {
@@ -222,31 +281,96 @@ func DecodeString(str string) (Packet, error) {
 			for j < len(str) && str[j] >= '0' && str[j] <= '9' {
 				j++
 			}
-			num, _ := strconv.Atoi(str[i:j])
+			num, err := strconv.Atoi(str[i:j])
+			if err != nil {
+				return ErrorInvalidPacket
+			}
 			packet.Id = num
 			packet.NeedAck = true
 			i = j
 		}
 
 		if i < len(str) {
-			data := str[i:]
-			packet.Data = tryParse(data)
+			data, err := tryParse(str[i:])
+			if err != nil {
+				return ErrorInvalidPacket
+			}
+			packet.Data = data
 		}
 
+		return nil
 	}()
 
-	return packet, nil
+	return packet, err
 }
 
-func tryParse(data string) interface{} {
+func tryParse(data string) (interface{}, error) {
 	var result interface{}
-	if err := json.Unmarshal([]byte(data), &result); err != nil {
-		return nil
+	if err := numberJson.UnmarshalFromString(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+/*
+*
+Returns v with its json.Number turned into float64, as encoding/json decodes
+them into interface{}, the slices and maps holding one are copied
+*/
+func Float64Numbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		if !hasNumber(v) {
+			return v
+		}
+		values := make([]interface{}, len(v))
+		for i, value := range v {
+			values[i] = Float64Numbers(value)
+		}
+		return values
+	case map[string]interface{}:
+		if !hasNumber(v) {
+			return v
+		}
+		values := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			values[key] = Float64Numbers(value)
+		}
+		return values
+	default:
+		return v
+	}
+}
+
+func hasNumber(v interface{}) bool {
+	switch v := v.(type) {
+	case json.Number:
+		return true
+	case []interface{}:
+		for _, value := range v {
+			if hasNumber(value) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for _, value := range v {
+			if hasNumber(value) {
+				return true
+			}
+		}
 	}
-	return result
+	return false
 }
 
-func (br *BinaryReconstructor) takeBinaryData(binData []byte) *Packet {
+/*
+*
+Adds an attachment, returns the packet with its placeholders
+replaced once all the attachments are received
+*/
+func (br *BinaryReconstructor) TakeBinaryData(binData []byte) *Packet {
 	br.buffers = append(br.buffers, binData)
 	if len(br.buffers) == br.packet.Attachments {
 		packet := reconstructPacket(br.packet, br.buffers)
@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -16,7 +18,7 @@ func TestEncodeString(t *testing.T) {
 		},
 	}
 
-	encodedPackets := encodeAsString(pkt)
+	encodedPackets := EncodeString(pkt)
 	fmt.Println("Encoded packets:", encodedPackets)
 }
 
@@ -43,3 +45,147 @@ func TestDecoderString(t *testing.T) {
 	pkg, _ = DecodeString(`51-/chat,123{"message":"Hello, World!"}`)
 	fmt.Println("DecodeString packets:", pkg)
 }
+
+func TestBinaryRoundTrip(t *testing.T) {
+	pkt := Packet{
+		Type:    EVENT,
+		Nsp:     "/chat",
+		Id:      12,
+		NeedAck: true,
+		Data:    []interface{}{"image", []byte{1, 2, 3}, map[string]interface{}{"thumb": []byte{4}}},
+	}
+
+	encoded := Encode(pkt)
+	if len(encoded) != 3 {
+		t.Fatalf("got %d frames, want 3", len(encoded))
+	}
+
+	header, err := DecodeString(string(encoded[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Type != BINARY_EVENT || header.Attachments != 2 || header.Nsp != "/chat" || header.Id != 12 {
+		t.Fatalf("bad header %+v", header)
+	}
+
+	reconstructor := NewBinaryReconstructor(header)
+	if p := reconstructor.TakeBinaryData(encoded[1]); p != nil {
+		t.Fatal("packet completed before its last attachment")
+	}
+	p := reconstructor.TakeBinaryData(encoded[2])
+	if p == nil {
+		t.Fatal("packet not completed")
+	}
+
+	want := []interface{}{"image", []byte{1, 2, 3}, map[string]interface{}{"thumb": []byte{4}}}
+	if !reflect.DeepEqual(p.Data, want) {
+		t.Fatalf("got %v, want %v", p.Data, want)
+	}
+}
+
+func TestDecodeStringAttachments(t *testing.T) {
+	pkt, err := DecodeString(`512-["image"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkt.Attachments != 12 {
+		t.Fatalf("got %d attachments, want 12", pkt.Attachments)
+	}
+
+	for _, str := range []string{``, `5`, `5x-[]`, `9[]`, `42["a",`, `4299999999999999999999["a"]`} {
+		if _, err := DecodeString(str); err != ErrorInvalidPacket {
+			t.Fatalf("%q: got %v, want %v", str, err, ErrorInvalidPacket)
+		}
+	}
+
+	if _, err := DecodeString(`599999999-["image"]`); err != ErrorTooManyAttachments {
+		t.Fatalf("got %v, want %v", err, ErrorTooManyAttachments)
+	}
+}
+
+func TestDecoderReconstructing(t *testing.T) {
+	var packets []*Packet
+	d := NewDecoder(func(packet *Packet) { packets = append(packets, packet) })
+
+	if err := d.Add(`51-["image"]`); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Add(`2["text"]`); err != ErrorReconstructing {
+		t.Fatalf("got %v, want %v", err, ErrorReconstructing)
+	}
+
+	// the packet being reconstructed is given up
+	if err := d.Add(`2["text"]`); err != nil || len(packets) != 1 {
+		t.Fatalf("got %v, %d packets", err, len(packets))
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	args := []interface{}{"message", "a,b[{1}]", map[string]interface{}{"n": json.Number("1"), "s": "/x,2["}}
+	binaryArgs := []interface{}{"image", []byte{1, 2}, map[string]interface{}{"b": []byte{3}}}
+
+	packets := []Packet{
+		{Type: CONNECT},
+		{Type: CONNECT, Data: map[string]interface{}{"token": "[{,"}},
+		{Type: DISCONNECT},
+		{Type: EVENT, Data: args},
+		{Type: ACK, Data: []interface{}{"ok", "1,[2]"}},
+		// above 2^53, not exact as a float64
+		{Type: ACK, Data: []interface{}{json.Number("1234567890123456789")}},
+		{Type: ACK, Data: []interface{}{}},
+		{Type: CONNECT_ERROR, Data: map[string]interface{}{"message": "not authorized"}},
+		{Type: EVENT, Data: binaryArgs},
+		{Type: ACK, Data: binaryArgs[1:]},
+	}
+
+	for _, nsp := range []string{"", "/", "/admin", "/chat[1]{x}"} {
+		for _, id := range []int{-1, 0, 12} {
+			for _, pkt := range packets {
+				pkt.Nsp = nsp
+				if id >= 0 {
+					pkt.Id = id
+					pkt.NeedAck = true
+				} else if pkt.Type == ACK {
+					continue
+				}
+
+				var decoded []*Packet
+				decoder := NewDecoder(func(p *Packet) {
+					decoded = append(decoded, p)
+				})
+
+				encoded := Encode(pkt)
+				for i, frame := range encoded {
+					var err error
+					if i == 0 {
+						err = decoder.Add(string(frame))
+					} else {
+						err = decoder.Add(frame)
+					}
+					if err != nil {
+						t.Fatalf("%q: %v", frame, err)
+					}
+				}
+
+				if len(decoded) != 1 {
+					t.Fatalf("%q: decoded %d packets", encoded[0], len(decoded))
+				}
+
+				want := pkt
+				if want.Nsp == "/" {
+					want.Nsp = ""
+				}
+				if len(encoded) > 1 {
+					want.Type = map[PacketType]PacketType{EVENT: BINARY_EVENT, ACK: BINARY_ACK}[want.Type]
+				}
+				if !want.NeedAck {
+					want.Id = 0
+				}
+
+				if !reflect.DeepEqual(*decoded[0], want) {
+					t.Fatalf("%q: got %+v, want %+v", encoded[0], *decoded[0], want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package pipe
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/SavvasMohito/go-socket.io-client/protocol"
+	"github.com/SavvasMohito/go-socket.io-client/utils"
+)
+
+const (
+	PipeDefaultPingInterval = 25 * time.Second
+	PipeDefaultPingTimeout  = 20 * time.Second
+	PipeDefaultBufferSize   = 1024
+
+	maxRecordReadBytes  = 1024 * 1024 * 1024
+	maxRecordWriteBytes = 1024 * 1024 * 1024
+
+	network = "pipe"
+)
+
+var (
+	ErrorPacketWrong = errors.New("wrong packet type error")
+	ErrorConnClosed  = errors.New("pipe connection closed")
+)
+
+/*
+*
+Address of an end of the pipe
+*/
+type Addr string
+
+func (a Addr) Network() string {
+	return network
+}
+
+func (a Addr) String() string {
+	return string(a)
+}
+
+/*
+*
+End of an in-memory engine.io connection, what is written to one end
+is read from the other, the way a websocket connection would deliver it
+*/
+type Connection struct {
+	transport *Transport
+
+	in   chan string
+	peer *Connection
+
+	// shared by both ends, closing one end closes the other
+	closed    chan struct{}
+	closeOnce *sync.Once
+
+	localAddr  Addr
+	remoteAddr Addr
+
+	bytesLock  sync.Mutex
+	writeBytes int
+	readBytes  int
+}
+
+func (pc *Connection) RemoteAddr() net.Addr {
+	return pc.remoteAddr
+}
+
+func (pc *Connection) LocalAddr() net.Addr {
+	return pc.localAddr
+}
+
+func (pc *Connection) GetProtocol() int {
+	return pc.transport.Protocol
+}
+
+func (pc *Connection) GetUseBinaryMessage() bool {
+	return false
+}
+
+func (pc *Connection) GetReadBytes() int {
+	pc.bytesLock.Lock()
+	defer pc.bytesLock.Unlock()
+
+	v := pc.readBytes
+	pc.readBytes = 0
+	return v
+}
+
+func (pc *Connection) GetWriteBytes() int {
+	pc.bytesLock.Lock()
+	defer pc.bytesLock.Unlock()
+
+	v := pc.writeBytes
+	pc.writeBytes = 0
+	return v
+}
+
+func (pc *Connection) GetMessage() (message string, err error) {
+	// the messages written before the close are still delivered
+	select {
+	case msg := <-pc.in:
+		return pc.received(msg), nil
+	default:
+	}
+
+	select {
+	case msg := <-pc.in:
+		return pc.received(msg), nil
+	case <-pc.closed:
+		return "", ErrorConnClosed
+	}
+}
+
+/*
+*
+Writes a string, binary attachments as []byte, and *protocol.MsgPack as text
+*/
+func (pc *Connection) WriteMessage(message interface{}) error {
+	utils.Debug("[WriteMessage]", message)
+
+	var msg string
+	switch m := message.(type) {
+	case string:
+		msg = m
+	case []byte:
+		msg = protocol.BinaryMsg + string(m)
+	case *protocol.MsgPack:
+		msg = protocol.EncodeTextMsg(m)
+	default:
+		return ErrorPacketWrong
+	}
+
+	select {
+	case <-pc.closed:
+		return ErrorConnClosed
+	default:
+	}
+
+	select {
+	case pc.peer.in <- msg:
+	case <-pc.closed:
+		return ErrorConnClosed
+	}
+
+	pc.bytesLock.Lock()
+	if pc.writeBytes > maxRecordWriteBytes {
+		pc.writeBytes = 0
+	}
+	pc.writeBytes += len(msg)
+	pc.bytesLock.Unlock()
+
+	return nil
+}
+
+func (pc *Connection) Close() {
+	pc.closeOnce.Do(func() {
+		close(pc.closed)
+	})
+}
+
+func (pc *Connection) PingParams() (interval, timeout time.Duration) {
+	return pc.transport.PingInterval, pc.transport.PingTimeout
+}
+
+func (pc *Connection) received(msg string) string {
+	utils.Debug("[GetMessage]", msg)
+
+	pc.bytesLock.Lock()
+	if pc.readBytes > maxRecordReadBytes {
+		pc.readBytes = 0
+	}
+	pc.readBytes += len(msg)
+	pc.bytesLock.Unlock()
+
+	return msg
+}
+
+type Transport struct {
+	PingInterval time.Duration
+	PingTimeout  time.Duration
+	Protocol     int
+	// BufferSize messages written and not read yet, a writer blocks beyond it
+	BufferSize int
+}
+
+/*
+*
+Returns the two ends of a new in-memory connection, the client end
+is returned by a Dialer and the server end is driven by the test
+*/
+func (pt *Transport) Pipe() (client *Connection, server *Connection) {
+	closed := make(chan struct{})
+	closeOnce := &sync.Once{}
+
+	client = &Connection{
+		transport:  pt,
+		in:         make(chan string, pt.BufferSize),
+		closed:     closed,
+		closeOnce:  closeOnce,
+		localAddr:  "client",
+		remoteAddr: "server",
+	}
+	server = &Connection{
+		transport:  pt,
+		in:         make(chan string, pt.BufferSize),
+		closed:     closed,
+		closeOnce:  closeOnce,
+		localAddr:  "server",
+		remoteAddr: "client",
+	}
+	client.peer = server
+	server.peer = client
+
+	return client, server
+}
+
+/*
+*
+Returns in-memory connection with default params
+*/
+func GetDefaultPipeTransport() *Transport {
+	return &Transport{
+		PingInterval: PipeDefaultPingInterval,
+		PingTimeout:  PipeDefaultPingTimeout,
+		Protocol:     protocol.Protocol4,
+		BufferSize:   PipeDefaultBufferSize,
+	}
+}
@@ -0,0 +1,47 @@
+package pipe
+
+import (
+	"testing"
+
+	"github.com/SavvasMohito/go-socket.io-client/protocol"
+)
+
+func TestPipe(t *testing.T) {
+	client, server := GetDefaultPipeTransport().Pipe()
+
+	messages := []interface{}{
+		`42["message"]`,
+		[]byte{0, 1},
+		&protocol.MsgPack{Type: protocol.EVENT, Nsp: "/admin", Data: []interface{}{"message"}, Id: 3},
+	}
+	want := []string{`42["message"]`, "b\x00\x01", `42/admin,3["message"]`}
+
+	for i, msg := range messages {
+		if err := client.WriteMessage(msg); err != nil {
+			t.Fatal(err)
+		}
+		got, err := server.GetMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want[i] {
+			t.Fatalf("got %q, want %q", got, want[i])
+		}
+	}
+
+	if err := server.WriteMessage("3"); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	// written before the close, still delivered
+	if got, err := client.GetMessage(); err != nil || got != "3" {
+		t.Fatalf("got %q %v", got, err)
+	}
+	if _, err := client.GetMessage(); err != ErrorConnClosed {
+		t.Fatalf("got %v, want %v", err, ErrorConnClosed)
+	}
+	if err := client.WriteMessage("2"); err != ErrorConnClosed {
+		t.Fatalf("got %v, want %v", err, ErrorConnClosed)
+	}
+}
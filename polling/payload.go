@@ -0,0 +1,151 @@
+package polling
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SavvasMohito/go-socket.io-client/protocol"
+)
+
+// https://github.com/socketio/engine.io-protocol#payload
+const (
+	// in protocol v4 the packets of a payload are joined with a record separator
+	recordSeparator = "\x1e"
+	// in protocol v3 every packet of a payload is prefixed with "<length>:"
+	lengthSeparator = ":"
+)
+
+/*
+*
+Splits a polling payload into engine.io packets
+
+v3: 6:4hello2:4€
+v4: 4hello\x1e4€
+*/
+func decodePayload(payload string, protocolV int) ([]string, error) {
+	if payload == "" {
+		return nil, nil
+	}
+
+	if protocolV != protocol.Protocol3 {
+		return strings.Split(payload, recordSeparator), nil
+	}
+
+	packets := make([]string, 0, 1)
+	for len(payload) > 0 {
+		i := strings.Index(payload, lengthSeparator)
+		if i < 1 {
+			return nil, ErrorBadPayload
+		}
+
+		length, err := strconv.Atoi(payload[:i])
+		if err != nil {
+			return nil, ErrorBadPayload
+		}
+		payload = payload[i+1:]
+
+		end, ok := utf16Offset(payload, length)
+		if !ok {
+			return nil, ErrorBadPayload
+		}
+
+		packets = append(packets, payload[:end])
+		payload = payload[end:]
+	}
+
+	return packets, nil
+}
+
+/*
+*
+Joins engine.io packets into a polling payload
+*/
+func encodePayload(packets []string, protocolV int) string {
+	if protocolV != protocol.Protocol3 {
+		return strings.Join(packets, recordSeparator)
+	}
+
+	var builder strings.Builder
+	for _, packet := range packets {
+		builder.WriteString(strconv.Itoa(utf16Len(packet)))
+		builder.WriteString(lengthSeparator)
+		builder.WriteString(packet)
+	}
+
+	return builder.String()
+}
+
+/*
+*
+Encodes a binary attachment as a payload packet, base64 after a "b"
+
+v3: b4AQI=
+v4: bAQI=
+*/
+func encodeBinary(data []byte, protocolV int) string {
+	packet := protocol.BinaryMsg
+	if protocolV == protocol.Protocol3 {
+		packet += protocol.CommonMsg
+	}
+
+	return packet + base64.StdEncoding.EncodeToString(data)
+}
+
+/*
+*
+Decodes a binary payload packet to "b" followed by the raw bytes,
+the way binary messages of the websocket transport are returned
+*/
+func decodeBinary(packet string, protocolV int) (string, error) {
+	data := packet[len(protocol.BinaryMsg):]
+	if protocolV == protocol.Protocol3 {
+		if !strings.HasPrefix(data, protocol.CommonMsg) {
+			return "", ErrorBadPayload
+		}
+		data = data[len(protocol.CommonMsg):]
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", ErrorBadPayload
+	}
+
+	return protocol.BinaryMsg + string(raw), nil
+}
+
+// the v3 length prefix counts characters the way javascript does, in utf-16 units
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeLen(r)
+	}
+
+	return n
+}
+
+// byte offset in s after the first n utf-16 units
+func utf16Offset(s string, n int) (int, bool) {
+	offset := 0
+	for n > 0 {
+		if offset >= len(s) {
+			return 0, false
+		}
+
+		r, size := utf8.DecodeRuneInString(s[offset:])
+		n -= runeLen(r)
+		offset += size
+	}
+
+	return offset, n == 0
+}
+
+// runes outside the basic multilingual plane take a surrogate pair
+func runeLen(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+
+	return 1
+}
@@ -0,0 +1,61 @@
+package polling
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SavvasMohito/go-socket.io-client/protocol"
+)
+
+func TestPayloadRoundTrip(t *testing.T) {
+	packets := []string{`0{"sid":"abc"}`, `40`, `42["message","€ 😀"]`, `6`}
+
+	for _, protocolV := range []int{protocol.Protocol3, protocol.Protocol4} {
+		payload := encodePayload(packets, protocolV)
+		decoded, err := decodePayload(payload, protocolV)
+		if err != nil {
+			t.Fatalf("v%d: %v", protocolV, err)
+		}
+		if !reflect.DeepEqual(decoded, packets) {
+			t.Fatalf("v%d: got %q, want %q", protocolV, decoded, packets)
+		}
+	}
+}
+
+func TestDecodePayloadV3(t *testing.T) {
+	// a surrogate pair counts as 2, the way javascript counts it
+	decoded, err := decodePayload("3:4😀6:4hello", protocol.Protocol3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"4😀", "4hello"}; !reflect.DeepEqual(decoded, want) {
+		t.Fatalf("got %q, want %q", decoded, want)
+	}
+
+	for _, payload := range []string{"4hello", "9:4hello", "x:4"} {
+		if _, err := decodePayload(payload, protocol.Protocol3); err != ErrorBadPayload {
+			t.Fatalf("%q: got %v, want %v", payload, err, ErrorBadPayload)
+		}
+	}
+}
+
+func TestBinaryPacket(t *testing.T) {
+	data := []byte{0, 1, 2, 0xff}
+
+	for _, protocolV := range []int{protocol.Protocol3, protocol.Protocol4} {
+		decoded, err := decodeBinary(encodeBinary(data, protocolV), protocolV)
+		if err != nil {
+			t.Fatalf("v%d: %v", protocolV, err)
+		}
+		if decoded != protocol.BinaryMsg+string(data) {
+			t.Fatalf("v%d: got %q", protocolV, decoded)
+		}
+	}
+
+	if got := encodeBinary(data, protocol.Protocol3); got != "b4AAEC/w==" {
+		t.Fatalf("got %q", got)
+	}
+	if _, err := decodeBinary("bAAEC/w==", protocol.Protocol3); err != ErrorBadPayload {
+		t.Fatalf("got %v, want %v", err, ErrorBadPayload)
+	}
+}
@@ -0,0 +1,561 @@
+package polling
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SavvasMohito/go-socket.io-client/protocol"
+	"github.com/SavvasMohito/go-socket.io-client/utils"
+	"github.com/SavvasMohito/go-socket.io-client/websocket"
+)
+
+const (
+	PollDefaultPingInterval   = 30 * time.Second
+	PollDefaultPingTimeout    = 60 * time.Second
+	PollDefaultReceiveTimeout = 60 * time.Second
+	PollDefaultSendTimeout    = 60 * time.Second
+
+	maxRecordReadBytes  = 1024 * 1024 * 1024
+	maxRecordWriteBytes = 1024 * 1024 * 1024
+
+	transportName   = "polling"
+	upgradeName     = "websocket"
+	payloadMimeType = "text/plain;charset=UTF-8"
+)
+
+var (
+	ErrorBadPayload   = errors.New("bad polling payload")
+	ErrorHandshake    = errors.New("polling handshake failed")
+	ErrorPacketWrong  = errors.New("wrong packet type error")
+	ErrorBadStatus    = errors.New("unexpected polling response status")
+	ErrorConnClosed   = errors.New("polling connection closed")
+	ErrorUpgradeProbe = errors.New("websocket probe failed")
+)
+
+/*
+*
+engine.io handshake fields needed by the polling transport
+*/
+type handshake struct {
+	Sid      string   `json:"sid"`
+	Upgrades []string `json:"upgrades"`
+}
+
+/*
+*
+Engine.IO connection over HTTP long-polling
+
+GetMessage runs the GET cycle and WriteMessage sends every message with a POST,
+so they can be used from a reading and a writing goroutine at the same time.
+Once the websocket probe succeeds, both are handed over to the websocket connection.
+*/
+type Connection struct {
+	transport *Transport
+	client    *http.Client
+	url       *url.URL
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+
+	queue     []string
+	queueLock sync.Mutex
+
+	// held while a GET or a POST request is in flight, the upgrade takes both
+	// to pause the polling transport before switching to the websocket one
+	pollLock  sync.Mutex
+	writeLock sync.Mutex
+
+	ws     *websocket.Connection
+	wsLock sync.RWMutex
+
+	remoteAddr net.Addr
+	localAddr  net.Addr
+	writeBytes int
+	readBytes  int
+}
+
+func (pc *Connection) RemoteAddr() net.Addr {
+	if ws := pc.upgraded(); ws != nil {
+		return ws.RemoteAddr()
+	}
+	return pc.remoteAddr
+}
+
+func (pc *Connection) LocalAddr() net.Addr {
+	if ws := pc.upgraded(); ws != nil {
+		return ws.LocalAddr()
+	}
+	return pc.localAddr
+}
+
+func (pc *Connection) GetProtocol() int {
+	return pc.transport.Protocol
+}
+
+/*
+*
+Long-polling always uses the text parser
+*/
+func (pc *Connection) GetUseBinaryMessage() bool {
+	return false
+}
+
+func (pc *Connection) GetReadBytes() int {
+	v := pc.readBytes
+	pc.readBytes = 0
+	if ws := pc.upgraded(); ws != nil {
+		v += ws.GetReadBytes()
+	}
+	return v
+}
+
+func (pc *Connection) GetWriteBytes() int {
+	v := pc.writeBytes
+	pc.writeBytes = 0
+	if ws := pc.upgraded(); ws != nil {
+		v += ws.GetWriteBytes()
+	}
+	return v
+}
+
+/*
+*
+Returns true when the connection has been upgraded to websocket
+*/
+func (pc *Connection) Upgraded() bool {
+	return pc.upgraded() != nil
+}
+
+func (pc *Connection) upgraded() *websocket.Connection {
+	pc.wsLock.RLock()
+	defer pc.wsLock.RUnlock()
+
+	return pc.ws
+}
+
+func (pc *Connection) GetMessage() (message string, err error) {
+	for {
+		if msg, ok := pc.shift(); ok {
+			if msg == protocol.NoopMsg {
+				continue
+			}
+			if strings.HasPrefix(msg, protocol.BinaryMsg) {
+				return decodeBinary(msg, pc.transport.Protocol)
+			}
+			utils.Debug("[GetMessage]", msg)
+			return msg, nil
+		}
+
+		if ws := pc.upgraded(); ws != nil {
+			return ws.GetMessage()
+		}
+
+		pc.pollLock.Lock()
+		if pc.upgraded() != nil {
+			pc.pollLock.Unlock()
+			continue
+		}
+		packets, err := pc.poll()
+		pc.pollLock.Unlock()
+		if err != nil {
+			return "", err
+		}
+
+		pc.push(packets...)
+	}
+}
+
+func (pc *Connection) WriteMessage(message interface{}) error {
+	utils.Debug("[WriteMessage]", message)
+
+	if ws := pc.upgraded(); ws != nil {
+		return ws.WriteMessage(message)
+	}
+
+	pc.writeLock.Lock()
+	defer pc.writeLock.Unlock()
+
+	// the upgrade may have completed while waiting for the lock
+	if ws := pc.upgraded(); ws != nil {
+		return ws.WriteMessage(message)
+	}
+
+	var packet string
+	switch msg := message.(type) {
+	case string:
+		packet = msg
+	case *protocol.MsgPack:
+		packet = protocol.EncodeTextMsg(msg)
+	case []byte:
+		packet = encodeBinary(msg, pc.transport.Protocol)
+	default:
+		return ErrorPacketWrong
+	}
+
+	return pc.post(pc.ctx, encodePayload([]string{packet}, pc.transport.Protocol))
+}
+
+func (pc *Connection) Close() {
+	pc.closeOnce.Do(func() {
+		if ws := pc.upgraded(); ws != nil {
+			ws.Close()
+		} else {
+			// let the server know, instead of waiting for its ping timeout
+			go func() {
+				err := pc.post(context.Background(), encodePayload([]string{protocol.CloseMsg}, pc.transport.Protocol))
+				if err != nil {
+					utils.Debug("[polling] close packet:", err)
+				}
+			}()
+		}
+
+		pc.cancel()
+	})
+}
+
+func (pc *Connection) PingParams() (interval, timeout time.Duration) {
+	return pc.transport.PingInterval, pc.transport.PingTimeout
+}
+
+func (pc *Connection) shift() (string, bool) {
+	pc.queueLock.Lock()
+	defer pc.queueLock.Unlock()
+
+	if len(pc.queue) == 0 {
+		return "", false
+	}
+
+	msg := pc.queue[0]
+	pc.queue = pc.queue[1:]
+	return msg, true
+}
+
+func (pc *Connection) push(packets ...string) {
+	pc.queueLock.Lock()
+	pc.queue = append(pc.queue, packets...)
+	pc.queueLock.Unlock()
+}
+
+/*
+*
+Request url with a fresh cache busting timestamp
+*/
+func (pc *Connection) requestUrl() string {
+	u := *pc.url
+	queryParams := u.Query()
+	queryParams.Set("t", strconv.FormatInt(time.Now().UnixNano(), 36))
+	u.RawQuery = queryParams.Encode()
+
+	return u.String()
+}
+
+func (pc *Connection) poll() ([]string, error) {
+	ctx, cancel := context.WithTimeout(pc.ctx, pc.transport.ReceiveTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pc.requestUrl(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := pc.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if pc.readBytes > maxRecordReadBytes {
+		pc.readBytes = 0
+	}
+	pc.readBytes += len(body)
+
+	return decodePayload(string(body), pc.transport.Protocol)
+}
+
+func (pc *Connection) post(ctx context.Context, payload string) error {
+	ctx, cancel := context.WithTimeout(ctx, pc.transport.SendTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pc.requestUrl(), strings.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", payloadMimeType)
+
+	if _, err := pc.do(req); err != nil {
+		return err
+	}
+
+	if pc.writeBytes > maxRecordWriteBytes {
+		pc.writeBytes = 0
+	}
+	pc.writeBytes += len(payload)
+	return nil
+}
+
+func (pc *Connection) do(req *http.Request) ([]byte, error) {
+	for k, v := range pc.transport.RequestHeader {
+		req.Header[k] = v
+	}
+
+	resp, err := pc.client.Do(req)
+	if err != nil {
+		if pc.ctx.Err() != nil {
+			return nil, ErrorConnClosed
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		utils.Debug("[polling] status:", resp.StatusCode, string(body))
+		return nil, ErrorBadStatus
+	}
+
+	return body, nil
+}
+
+/*
+*
+Probes the websocket transport and switches to it
+
+https://github.com/socketio/engine.io-protocol#upgrade
+*/
+func (pc *Connection) upgrade() {
+	u := *pc.url
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	}
+	queryParams := u.Query()
+	queryParams.Set("transport", upgradeName)
+	queryParams.Del("b64")
+	u.RawQuery = queryParams.Encode()
+
+	// the probe carries the cookies of the polling requests,
+	// sticky sessions route it to the node holding the session
+	tr := *pc.transport.Upgrade
+	tr.RequestHeader = pc.upgradeHeader()
+
+	ws, err := tr.Connect(u.String())
+	if err != nil {
+		utils.Debug("[polling] upgrade failed:", err)
+		return
+	}
+
+	if err := pc.probe(ws); err != nil {
+		utils.Debug("[polling] upgrade failed:", err)
+		ws.Close()
+		return
+	}
+
+	// pause polling, the server answers the in-flight GET with a noop
+	pc.pollLock.Lock()
+	defer pc.pollLock.Unlock()
+	pc.writeLock.Lock()
+	defer pc.writeLock.Unlock()
+
+	if pc.ctx.Err() != nil {
+		ws.Close()
+		return
+	}
+
+	if err := ws.WriteMessage(protocol.UpgradeMsg); err != nil {
+		utils.Debug("[polling] upgrade failed:", err)
+		ws.Close()
+		return
+	}
+
+	pc.wsLock.Lock()
+	pc.ws = ws
+	pc.wsLock.Unlock()
+
+	utils.Debug("[polling] upgraded to", upgradeName)
+}
+
+/*
+*
+Headers of the websocket probe, those of the upgrade transport
+and the cookies stored by the polling requests
+*/
+func (pc *Connection) upgradeHeader() http.Header {
+	header := pc.transport.Upgrade.RequestHeader.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	if pc.client.Jar == nil {
+		return header
+	}
+
+	req := &http.Request{Header: header}
+	for _, cookie := range pc.client.Jar.Cookies(pc.url) {
+		req.AddCookie(cookie)
+	}
+
+	return header
+}
+
+func (pc *Connection) probe(ws *websocket.Connection) error {
+	if err := ws.WriteMessage(protocol.PingMsg + protocol.ProbeMsg); err != nil {
+		return err
+	}
+
+	msg, err := ws.GetMessage()
+	if err != nil {
+		return err
+	}
+
+	if msg != protocol.PongMsg+protocol.ProbeMsg {
+		return ErrorUpgradeProbe
+	}
+
+	return nil
+}
+
+type Transport struct {
+	PingInterval   time.Duration
+	PingTimeout    time.Duration
+	ReceiveTimeout time.Duration
+	SendTimeout    time.Duration
+
+	Protocol int
+
+	UnsecureTLS bool
+	TLSConfig   *tls.Config
+
+	RequestHeader http.Header
+
+	// HttpClient is used for every request, a client with a cookie jar
+	// is created when nil, so sticky sessions keep working
+	HttpClient *http.Client
+
+	// Upgrade is used to probe and switch to websocket once the handshake
+	// lists it in its upgrades, nil keeps the connection on long-polling
+	Upgrade *websocket.Transport
+}
+
+func (pt *Transport) Connect(addr string) (conn *Connection, err error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	}
+
+	queryParams := u.Query()
+	queryParams.Set("transport", transportName)
+	if pt.Protocol == protocol.Protocol3 {
+		// binary packets as base64 strings instead of the v3 binary payload
+		queryParams.Set("b64", "1")
+	}
+	u.RawQuery = queryParams.Encode()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pc := &Connection{
+		transport: pt,
+		client:    pt.httpClient(),
+		url:       u,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			pc.remoteAddr = info.Conn.RemoteAddr()
+			pc.localAddr = info.Conn.LocalAddr()
+		},
+	}
+	pc.ctx = httptrace.WithClientTrace(ctx, trace)
+
+	packets, err := pc.poll()
+	pc.ctx = ctx
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if len(packets) == 0 || !strings.HasPrefix(packets[0], protocol.OpenMsg) {
+		cancel()
+		return nil, ErrorHandshake
+	}
+
+	var h handshake
+	if err := utils.Json.UnmarshalFromString(packets[0][1:], &h); err != nil || h.Sid == "" {
+		cancel()
+		return nil, ErrorHandshake
+	}
+
+	queryParams.Set("sid", h.Sid)
+	u.RawQuery = queryParams.Encode()
+
+	// the handshake is handed to the client like any other message
+	pc.push(packets...)
+
+	if pt.Upgrade != nil {
+		for _, upgrade := range h.Upgrades {
+			if upgrade == upgradeName {
+				go pc.upgrade()
+				break
+			}
+		}
+	}
+
+	return pc, nil
+}
+
+func (pt *Transport) httpClient() *http.Client {
+	if pt.HttpClient != nil {
+		return pt.HttpClient
+	}
+
+	tlsCfg := pt.TLSConfig
+	if tlsCfg == nil {
+		tlsCfg = &tls.Config{InsecureSkipVerify: pt.UnsecureTLS}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+
+	jar, _ := cookiejar.New(nil)
+	return &http.Client{Transport: transport, Jar: jar}
+}
+
+/*
+*
+Returns long-polling connection with default params
+*/
+func GetDefaultPollingTransport() *Transport {
+	return &Transport{
+		Protocol:       protocol.Protocol4,
+		PingInterval:   PollDefaultPingInterval,
+		PingTimeout:    PollDefaultPingTimeout,
+		ReceiveTimeout: PollDefaultReceiveTimeout,
+		SendTimeout:    PollDefaultSendTimeout,
+		UnsecureTLS:    false,
+		TLSConfig:      nil,
+	}
+}
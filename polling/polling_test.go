@@ -0,0 +1,62 @@
+package polling
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SavvasMohito/go-socket.io-client/protocol"
+	"github.com/SavvasMohito/go-socket.io-client/websocket"
+)
+
+func TestUpgradeCookies(t *testing.T) {
+	cookies := make(chan string, 1)
+	wst := websocket.GetDefaultWebsocketTransport()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("transport") == "websocket" {
+			cookies <- r.Header.Get("Cookie")
+			conn, err := wst.HandleConnection(w, r)
+			if err != nil {
+				return
+			}
+			conn.Close()
+			return
+		}
+
+		if r.URL.Query().Get("sid") == "" {
+			http.SetCookie(w, &http.Cookie{Name: "route", Value: "node-1"})
+			w.Write([]byte(`0{"sid":"abc","upgrades":["websocket"],"pingInterval":25000,"pingTimeout":20000}`))
+			return
+		}
+
+		// the next poll waits for the test to end
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	tr := GetDefaultPollingTransport()
+	tr.Upgrade = wst
+	wst.RequestHeader = http.Header{"X-Test": []string{"1"}}
+
+	conn, err := tr.Connect(strings.Replace(srv.URL, "http", "ws", 1) + "/socket.io/?EIO=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if msg, _ := conn.GetMessage(); !strings.HasPrefix(msg, protocol.OpenMsg) {
+		t.Fatalf("got %q", msg)
+	}
+
+	select {
+	case cookie := <-cookies:
+		if cookie != "route=node-1" {
+			t.Fatalf("got cookie %q", cookie)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no websocket probe")
+	}
+}
@@ -0,0 +1,151 @@
+package socketio_test
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/parser"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+	"github.com/SavvasMohito/go-socket.io-client/websocket"
+)
+
+func TestHandlerPool(t *testing.T) {
+	for _, policy := range []socketio.OverloadPolicy{socketio.OverloadBlock, socketio.OverloadDrop, socketio.OverloadDisconnect} {
+		srv := sockettest.NewServer()
+		defer srv.Close()
+
+		b := &socketio.ClientBuilder{}
+		c := newTestClient(t, srv.URL, b.WithMaxHandlers(2), b.WithHandlerQueueSize(5), b.WithHandlerOverload(policy))
+
+		var running, maxRunning, handled, overloads atomic.Int32
+		gate := make(chan struct{})
+		c.On("work", func(ch *socketio.Channel, i int) {
+			n := running.Add(1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			<-gate
+			running.Add(-1)
+			handled.Add(1)
+		})
+		overloaded := make(chan struct{}, 20)
+		c.On(socketio.OnOverload, func(ch *socketio.Channel, err *socketio.OverloadError) {
+			if err.Policy != policy || err.Event != "work" {
+				t.Errorf("got %+v", err)
+			}
+			overloads.Add(1)
+			overloaded <- struct{}{}
+		})
+		closed := make(chan int, 4)
+		c.On(socketio.OnDisconnection, func(ch *socketio.Channel, err *websocket.CloseError) {
+			closed <- err.Code
+		})
+
+		connectTestClient(t, c)
+		conn, err := srv.Conn(testTimeout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 20; i++ {
+			conn.Emit("/", "work", i)
+		}
+
+		// reported before the reader blocks with OverloadBlock
+		waitSignal(t, overloaded, "overload")
+		time.Sleep(50 * time.Millisecond)
+		close(gate)
+		time.Sleep(100 * time.Millisecond)
+
+		if maxRunning.Load() > 2 {
+			t.Fatalf("%d handlers at the same time", maxRunning.Load())
+		}
+		switch policy {
+		case socketio.OverloadBlock:
+			if handled.Load() != 20 {
+				t.Fatalf("block: %d handled", handled.Load())
+			}
+		case socketio.OverloadDrop:
+			if handled.Load() != 7 || overloads.Load() != 13 {
+				t.Fatalf("drop: %d handled, %d overloads", handled.Load(), overloads.Load())
+			}
+		case socketio.OverloadDisconnect:
+			select {
+			case code := <-closed:
+				if code != socketio.HandlerOverloadCode {
+					t.Fatalf("disconnect: closed with %d", code)
+				}
+			default:
+				t.Fatal("disconnect: not closed")
+			}
+		}
+	}
+}
+
+func TestHandlerPoolAck(t *testing.T) {
+	d := newPipeDialer()
+	b := &socketio.ClientBuilder{}
+	c := newTestClient(t, "http://localhost", d.option(), b.WithMaxHandlers(1))
+
+	// the only handler awaits an ack answered while it runs
+	answers := make(chan error, 1)
+	c.On("ask", func(ch *socketio.Channel) {
+		_, err := ch.Ack("q", testTimeout)
+		answers <- err
+	})
+	c.Connect()
+
+	srv := d.accept(t)
+	srv.WriteMessage(`42["ask"]`)
+	p, err := parser.DecodeString(readMessage(t, srv)[1:])
+	if err != nil || !p.NeedAck {
+		t.Fatal(p, err)
+	}
+	srv.WriteMessage("43" + strconv.Itoa(p.Id) + `["r"]`)
+
+	select {
+	case err := <-answers:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("deadlock")
+	}
+}
+
+func TestHandlerPoolControl(t *testing.T) {
+	d := newPipeDialer()
+	b := &socketio.ClientBuilder{}
+	c := newTestClient(t, "http://localhost", d.option(), b.WithMaxHandlers(1), b.WithHandlerQueueSize(1),
+		b.WithHandlerOverload(socketio.OverloadDrop))
+
+	gate := make(chan struct{})
+	defer close(gate)
+	c.On("work", func(ch *socketio.Channel) { <-gate })
+	closed := make(chan int, 1)
+	c.On(socketio.OnDisconnection, func(ch *socketio.Channel, err *websocket.CloseError) {
+		closed <- err.Code
+	})
+	c.Connect()
+
+	// the DISCONNECT is neither dropped nor waits for the busy handler
+	srv := d.accept(t)
+	for i := 0; i < 5; i++ {
+		srv.WriteMessage(`42["work"]`)
+	}
+	srv.WriteMessage(`41`)
+
+	select {
+	case code := <-closed:
+		if code != socketio.ServerDisconnectCode {
+			t.Fatalf("closed with %d", code)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("DISCONNECT not handled")
+	}
+}
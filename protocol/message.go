@@ -23,8 +23,14 @@ const (
 	PongMsg    = "3"
 	CommonMsg  = "4"
 	UpgradeMsg = "5"
+	NoopMsg    = "6"
+	// binary attachment in text mode, followed by the raw bytes
+	BinaryMsg = "b"
 )
 
+// payload sent with ping and pong while probing a transport upgrade
+const ProbeMsg = "probe"
+
 type MsgPack struct {
 	Type int         `json:"type"`
 	Data interface{} `json:"data"`
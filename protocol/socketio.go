@@ -1,5 +1,9 @@
 package protocol
 
+import (
+	"github.com/SavvasMohito/go-socket.io-client/parser"
+)
+
 // https://github.com/socketio/socket.io-protocol#connection-to-a-namespace
 const (
 	Protocol3 = 3
@@ -23,3 +27,44 @@ func GetMsgPacket(msg *Message) *MsgPack {
 		Id:   msg.AckId,
 	}
 }
+
+/*
+*
+Encodes packet as an engine.io text message
+
+	{
+	 "type": 3,
+	 "nsp": "/admin",
+	 "data": [],
+	 "id": 456
+	}
+
+is encoded to 43/admin,456[]
+*/
+func EncodeTextMsg(msg *MsgPack) string {
+	// Engine.IO Flag + Socket.IO packet
+	return CommonMsg + parser.EncodeString(ParserPacket(msg))
+}
+
+/*
+*
+Returns msg as a packet of the parser, the id is sent with acks
+and with events waiting for an ack
+*/
+func ParserPacket(msg *MsgPack) parser.Packet {
+	needAck := false
+	switch msg.Type {
+	case ACK, BINARY_ACK:
+		needAck = true
+	case EVENT, BINARY_EVENT:
+		needAck = msg.Id >= 0
+	}
+
+	return parser.Packet{
+		Type:    parser.PacketType(msg.Type),
+		Nsp:     msg.Nsp,
+		Data:    msg.Data,
+		Id:      msg.Id,
+		NeedAck: needAck,
+	}
+}
@@ -0,0 +1,60 @@
+package socketio_test
+
+import (
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+)
+
+func TestReconnect(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	b := &socketio.ClientBuilder{}
+	c := newTestClient(t, srv.URL, b.WithReconnection(true), b.WithReconnectionDelay(10*time.Millisecond))
+
+	reconnected := make(chan struct{}, 1)
+	c.On(socketio.OnReconnect, func(ch *socketio.Channel, attempt int) {
+		reconnected <- struct{}{}
+	})
+	connectTestClient(t, c)
+
+	conn, err := srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Drop()
+	waitSignal(t, reconnected, "reconnection")
+
+	// no reconnection once the server disconnected the namespace
+	conn, err = srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Disconnect("/")
+	if _, err := srv.Conn(200 * time.Millisecond); err == nil {
+		t.Fatal("reconnected after a server disconnect")
+	}
+}
+
+func TestReconnectFailed(t *testing.T) {
+	srv := sockettest.NewServer()
+
+	b := &socketio.ClientBuilder{}
+	c := newTestClient(t, srv.URL, b.WithReconnection(true), b.WithReconnectionAttempts(2),
+		b.WithReconnectionDelay(10*time.Millisecond), b.WithReconnectionDelayMax(20*time.Millisecond))
+
+	attempts := make(chan int, 4)
+	failed := make(chan struct{}, 1)
+	c.On(socketio.OnReconnectAttempt, func(ch *socketio.Channel, attempt int) { attempts <- attempt })
+	c.On(socketio.OnReconnectFailed, func(ch *socketio.Channel) { failed <- struct{}{} })
+	connectTestClient(t, c)
+
+	srv.Close()
+	waitSignal(t, failed, "reconnect_failed")
+	if len(attempts) != 2 {
+		t.Fatalf("got %d attempts", len(attempts))
+	}
+}
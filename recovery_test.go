@@ -0,0 +1,46 @@
+package socketio_test
+
+import (
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+)
+
+func TestRecovery(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		connect string
+		want    string
+	}{
+		{"recovery", `40{"sid":"n","pid":"P1"}`, `40{"offset":"off-7","pid":"P1"}`},
+		// a trailing string is not an offset without a pid
+		{"no recovery", `40{"sid":"n"}`, "40"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newPipeDialer()
+			b := &socketio.ClientBuilder{}
+			c := newTestClient(t, "http://localhost", d.option(),
+				b.WithReconnection(true), b.WithReconnectionDelay(10*time.Millisecond))
+
+			news := make(chan struct{}, 1)
+			c.On("news", func(ch *socketio.Channel, s string) { news <- struct{}{} })
+			c.Connect()
+
+			srv := d.open(t)
+			if msg := readMessage(t, srv); msg != "40" {
+				t.Fatalf("got %q", msg)
+			}
+			srv.WriteMessage(tc.connect)
+			// the missed events are sent right after the CONNECT
+			srv.WriteMessage(`42["news","off-7"]`)
+			waitSignal(t, news, "event")
+			srv.Close()
+
+			srv = d.open(t)
+			if msg := readMessage(t, srv); msg != tc.want {
+				t.Fatalf("got %q, want %q", msg, tc.want)
+			}
+		})
+	}
+}
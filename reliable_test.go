@@ -0,0 +1,121 @@
+package socketio_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+)
+
+func TestEmitReliable(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	b := &socketio.ClientBuilder{}
+	c := newTestClient(t, srv.URL, b.WithRetries(2), b.WithAckTimeout(50*time.Millisecond),
+		b.WithRetryDelay(10*time.Millisecond), b.WithReconnection(true),
+		b.WithReconnectionDelay(10*time.Millisecond))
+	connectTestClient(t, c)
+	conn, err := srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitBill := func(conn *sockettest.Conn, n float64) int {
+		t.Helper()
+
+		p, err := conn.WaitEvent(testTimeout, "/", "bill")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := p.Data.([]interface{})[1]; v != n {
+			t.Fatalf("got bill %v, want %v", v, n)
+		}
+		return p.Id
+	}
+
+	f1 := c.EmitReliable("bill", 1)
+	f2 := c.EmitReliable("bill", 2)
+
+	// the first attempt times out, 2 is not sent before 1 is acknowledged
+	waitBill(conn, 1)
+	conn.Ack("/", waitBill(conn, 1), "one")
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	if r, err := socketio.AwaitAs[string](ctx, f1); err != nil || r != "one" {
+		t.Fatal(r, err)
+	}
+
+	// never acknowledged, given up after the retries
+	for i := 0; i < 3; i++ {
+		waitBill(conn, 2)
+	}
+	if _, err := f2.Await(ctx); err != socketio.ErrorSendTimeout {
+		t.Fatalf("got %v, want ErrorSendTimeout", err)
+	}
+
+	// survives a reconnection
+	f3 := c.EmitReliable("bill", 3)
+	waitBill(conn, 3)
+	conn.Drop()
+	conn, err = srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Ack("/", waitBill(conn, 3), "three")
+	if r, err := socketio.AwaitAs[string](ctx, f3); err != nil || r != "three" {
+		t.Fatal(r, err)
+	}
+
+	f4 := c.EmitReliable("bill", 4)
+	c.Close()
+	if _, err := f4.Await(ctx); err != socketio.ErrorDisconnected {
+		t.Fatalf("got %v, want ErrorDisconnected", err)
+	}
+}
+
+func TestEmitReliableOffline(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	// offline for longer than the ack timeout
+	b := &socketio.ClientBuilder{}
+	c := newTestClient(t, srv.URL, b.WithAckTimeout(100*time.Millisecond), b.WithAckReconnectRetries(1),
+		b.WithReconnection(true), b.WithReconnectionDelay(300*time.Millisecond),
+		b.WithReconnectionDelayMax(300*time.Millisecond))
+	connectTestClient(t, c)
+	conn, err := srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := c.EmitReliable("bill")
+	if _, err := conn.WaitEvent(testTimeout, "/", "bill"); err != nil {
+		t.Fatal(err)
+	}
+	conn.Drop()
+
+	// the attempt is sent again and times out from there only
+	conn, err = srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := conn.WaitEvent(testTimeout, "/", "bill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isDone(f) {
+		r, err := f.Result()
+		t.Fatalf("resolved while offline: %v %v", r, err)
+	}
+
+	conn.Ack("/", p.Id, "ok")
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	if r, err := socketio.AwaitAs[string](ctx, f); err != nil || r != "ok" {
+		t.Fatal(r, err)
+	}
+}
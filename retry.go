@@ -0,0 +1,219 @@
+package socketio
+
+import (
+	"sync"
+	"time"
+
+	"github.com/SavvasMohito/go-socket.io-client/utils"
+)
+
+const (
+	defaultAckTimeout    = 10 * time.Second
+	defaultRetryDelay    = 500 * time.Millisecond
+	defaultRetryDelayMax = 5 * time.Second
+)
+
+type retryPacket struct {
+	method   string
+	args     []interface{}
+	future   *AckFuture
+	attempts int
+}
+
+/*
+*
+Events emitted with EmitReliable, sent one at a time in order,
+the next one is sent once the previous one is acknowledged or given up
+*/
+type retryQueue struct {
+	retries int
+	timeout time.Duration
+	backoff backoff
+
+	packets []*retryPacket
+	// the head is sent and not acknowledged yet, or waits for its next attempt
+	inFlight bool
+	// times out the attempt in flight, stopped while it is kept on disconnection
+	timer *time.Timer
+	lock  sync.Mutex
+}
+
+func (q *retryQueue) push(c *Channel, p *retryPacket) {
+	q.lock.Lock()
+	q.packets = append(q.packets, p)
+	q.lock.Unlock()
+
+	q.drain(c)
+}
+
+/*
+*
+Sends the head of the queue unless it is already in flight
+or the namespace is not connected
+*/
+func (q *retryQueue) drain(c *Channel) {
+	q.lock.Lock()
+	// the heads abandoned by their caller are skipped
+	for len(q.packets) > 0 && isResolved(q.packets[0].future) {
+		q.packets = q.packets[1:]
+	}
+	if q.inFlight || len(q.packets) == 0 || !c.IsAlive() {
+		q.lock.Unlock()
+		return
+	}
+	head := q.packets[0]
+	q.inFlight = true
+	q.lock.Unlock()
+
+	attempt := c.EmitWithAck(head.method, head.args...)
+	timer := time.AfterFunc(q.timeout, func() {
+		attempt.resolve(nil, nil, ErrorSendTimeout)
+	})
+	q.lock.Lock()
+	q.timer = timer
+	if !c.IsAlive() {
+		// disconnected meanwhile, started again by resume
+		timer.Stop()
+	}
+	q.lock.Unlock()
+
+	attempt.Then(func(result interface{}, err error) {
+		q.lock.Lock()
+		timer.Stop()
+		if q.timer == timer {
+			q.timer = nil
+		}
+		q.lock.Unlock()
+
+		q.answered(c, head, attempt, err)
+	})
+}
+
+/*
+*
+Stops the timeout of the attempt in flight, it is kept on disconnection
+and sent again once the namespace is connected
+*/
+func (q *retryQueue) pause() {
+	q.lock.Lock()
+	if q.timer != nil {
+		q.timer.Stop()
+	}
+	q.lock.Unlock()
+}
+
+/*
+*
+Starts the timeout of the attempt in flight again, once it is sent again
+*/
+func (q *retryQueue) resume() {
+	q.lock.Lock()
+	if q.timer != nil {
+		q.timer.Reset(q.timeout)
+	}
+	q.lock.Unlock()
+}
+
+/*
+*
+Moves on to the next packet once the head is acknowledged or out of retries,
+otherwise sends the head again after the backoff delay
+*/
+func (q *retryQueue) answered(c *Channel, head *retryPacket, attempt *AckFuture, err error) {
+	q.lock.Lock()
+	if len(q.packets) == 0 || q.packets[0] != head {
+		// the queue was failed meanwhile
+		q.inFlight = false
+		q.lock.Unlock()
+		q.drain(c)
+		return
+	}
+
+	switch {
+	case err == nil:
+		q.packets = q.packets[1:]
+		q.backoff.reset()
+	case err == ErrorDisconnected || err == ErrorNotConnected:
+		// sent again once the namespace is connected
+		q.inFlight = false
+		q.lock.Unlock()
+		q.drain(c)
+		return
+	default:
+		head.attempts++
+		if head.attempts > q.retries {
+			utils.Debug("[retry] gave up:", head.method, err)
+			q.packets = q.packets[1:]
+			q.backoff.reset()
+			break
+		}
+
+		delay := q.backoff.duration()
+		q.lock.Unlock()
+
+		utils.Debug("[retry]", head.method, "attempt", head.attempts, "in", delay)
+		time.AfterFunc(delay, func() {
+			q.lock.Lock()
+			q.inFlight = false
+			q.lock.Unlock()
+			q.drain(c)
+		})
+		return
+	}
+
+	q.inFlight = false
+	q.lock.Unlock()
+
+	attempt.lock.Lock()
+	result, values := attempt.result, attempt.values
+	attempt.lock.Unlock()
+	head.future.resolve(result, values, err)
+
+	q.drain(c)
+}
+
+/*
+*
+Fails the queued packets, the namespace is not connected again
+*/
+func (q *retryQueue) fail(err error) {
+	q.lock.Lock()
+	packets := q.packets
+	q.packets = nil
+	q.lock.Unlock()
+
+	for _, p := range packets {
+		p.future.resolve(nil, nil, err)
+	}
+}
+
+func isResolved(f *AckFuture) bool {
+	select {
+	case <-f.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+/*
+*
+Emits an event with at-least-once delivery, it is sent again when not
+acknowledged within AckTimeout, up to Retries times, and the events
+are delivered in order, one at a time. The answer of the server,
+or the last error, resolves the returned future.
+*/
+func (c *Channel) EmitReliable(method string, args ...interface{}) *AckFuture {
+	future := &AckFuture{
+		id:   -1,
+		done: make(chan struct{}),
+	}
+
+	c.ack.queue.push(c, &retryPacket{
+		method: method,
+		args:   args,
+		future: future,
+	})
+
+	return future
+}
@@ -1,79 +1,173 @@
 package socketio
 
 import (
+	"context"
 	"errors"
 	"log"
 	"time"
 
-	"github.com/mark0725/go-socket.io-client/protocol"
+	"github.com/SavvasMohito/go-socket.io-client/protocol"
 )
 
 var (
 	ErrorSendTimeout     = errors.New("timeout")
 	ErrorSocketOverflood = errors.New("socket overflood")
+	ErrorNotConnected    = errors.New("namespace not connected")
 )
 
 /*
 *
-Send message packet to socket
+Send message packet to socket, fails with ErrorNotConnected when it is lost
+because the namespace is not connected and the buffer is disabled
 */
 func send(c *Channel, msg *protocol.Message) error {
+	sent, err := sendMsg(c, msg)
+	if err == nil && !sent {
+		return ErrorNotConnected
+	}
+	return err
+}
+
+/*
+*
+Send message packet to socket, returns false when it is lost
+because the namespace is not connected and the buffer is disabled
+*/
+func sendMsg(c *Channel, msg *protocol.Message) (sent bool, err error) {
+	return queueMsg(context.Background(), c, msg, false)
+}
+
+/*
+*
+Queues the packet of msg, when the queue is full it fails with
+ErrorSocketOverflood, or waits for room until ctx is done when wait is true
+*/
+func queueMsg(ctx context.Context, c *Channel, msg *protocol.Message, wait bool) (sent bool, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			log.Println("socket.io send panic: ", r)
 		}
 	}()
 
-	if !c.IsAlive() {
-		return nil
+	if msg.Type == protocol.EVENT {
+		c.anyOutgoing.call(c, msg.Method, msg.Args)
 	}
 
 	out := protocol.GetMsgPacket(msg)
 
-	if len(c.out) == queueBufferSize {
-		return ErrorSocketOverflood
+	// held until the namespace is connected
+	buffered, err := c.buffer.push(msg, out)
+	if buffered || err != nil {
+		return buffered, err
 	}
 
-	c.out <- out
-
-	return nil
-}
+	if !c.IsAlive() {
+		return false, nil
+	}
 
-func (c *Channel) Emit(method string, args ...interface{}) error {
-	msg := &protocol.Message{
-		Type:   protocol.EVENT,
-		AckId:  -1,
-		Method: method,
-		Nsp:    c.namespace,
-		Args:   args,
+	queue := c.queue()
+	if !wait {
+		select {
+		case queue.data <- out:
+		default:
+			return false, ErrorSocketOverflood
+		}
+	} else {
+		select {
+		case queue.data <- out:
+		case <-queue.done:
+			return false, ErrorNotConnected
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
 	}
+	queue.pushed()
 
-	return send(c, msg)
+	return true, nil
 }
 
-func (c *Channel) Ack(method string, timeout time.Duration, args ...interface{}) (interface{}, error) {
-	msg := &protocol.Message{
+/*
+*
+EVENT of the namespace of c, ackId is -1 when no ack is requested
+*/
+func (c *Channel) eventMsg(method string, ackId int, args []interface{}) *protocol.Message {
+	return &protocol.Message{
 		Type:   protocol.EVENT,
-		AckId:  c.ack.getNextId(),
+		AckId:  ackId,
 		Method: method,
 		Nsp:    c.namespace,
 		Args:   args,
 	}
+}
 
-	waiter := make(chan interface{})
-	c.ack.addWaiter(msg.AckId, waiter)
+/*
+*
+Emits an event without waiting, fails right away with ErrorSocketOverflood
+when the outgoing queue is full, see EmitContext to wait for room. Returns
+ErrorNotConnected when the event is lost because the namespace is not
+connected and the buffer is disabled.
+*/
+func (c *Channel) Emit(method string, args ...interface{}) error {
+	return send(c, c.eventMsg(method, -1, args))
+}
+
+/*
+*
+Emits an event and returns right away, the answer of the server
+resolves the returned future. It fails with ErrorNotConnected when the event
+is not sent because the namespace is not connected and the buffer is disabled,
+and with ErrorDisconnected when the connection is lost before the answer.
+*/
+func (c *Channel) EmitWithAck(method string, args ...interface{}) *AckFuture {
+	msg := c.eventMsg(method, c.ack.getNextId(), args)
+	future := newAckFuture(msg, &c.ack)
+	c.ack.addWaiter(msg.AckId, future)
 
-	err := send(c, msg)
+	sent, err := sendMsg(c, msg)
 	if err != nil {
-		c.ack.removeWaiter(msg.AckId)
+		future.resolve(nil, nil, err)
+	} else if !sent {
+		future.resolve(nil, nil, ErrorNotConnected)
 	}
 
-	select {
-	case result := <-waiter:
-		c.ack.removeWaiter(msg.AckId)
-		return result, nil
-	case <-time.After(timeout):
-		c.ack.removeWaiter(msg.AckId)
+	return future
+}
+
+/*
+*
+Emits an event and waits up to timeout for the answer of the server
+*/
+func (c *Channel) Ack(method string, timeout time.Duration, args ...interface{}) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result, err := c.EmitWithAck(method, args...).Await(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
 		return nil, ErrorSendTimeout
 	}
+
+	return result, err
+}
+
+/*
+*
+Emits an event, waits for room in the outgoing queue while it is full
+until ctx is done. Returns ErrorNotConnected when the event is lost
+because the namespace is not connected and the buffer is disabled.
+*/
+func (c *Channel) EmitContext(ctx context.Context, method string, args ...interface{}) error {
+	sent, err := queueMsg(ctx, c, c.eventMsg(method, -1, args), true)
+	if err == nil && !sent {
+		return ErrorNotConnected
+	}
+	return err
+}
+
+/*
+*
+Same as Emit, named for its counterpart EmitContext which waits for room
+in the outgoing queue
+*/
+func (c *Channel) TryEmit(method string, args ...interface{}) error {
+	return c.Emit(method, args...)
 }
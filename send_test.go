@@ -0,0 +1,71 @@
+package socketio_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/parser"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+)
+
+func TestSendBuffer(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	b := &socketio.ClientBuilder{}
+	c := newTestClient(t, srv.URL, b.WithSendBuffer(2, 0, socketio.DropOldest))
+
+	drops := make(chan *socketio.DropError, 1)
+	c.On(socketio.OnBufferDrop, func(ch *socketio.Channel, err *socketio.DropError) { drops <- err })
+
+	future := c.EmitWithAck("a")
+	c.Emit("b")
+	c.Emit("c")
+
+	if _, err := future.Result(); !errors.Is(err, socketio.ErrorSendBufferFull) {
+		t.Fatalf("got %v, want ErrorSendBufferFull", err)
+	}
+	select {
+	case err := <-drops:
+		if err.Event != "a" || !errors.Is(err, socketio.ErrorSendBufferFull) {
+			t.Fatal(err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("no buffer_drop")
+	}
+
+	connectTestClient(t, c)
+	conn, err := srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.WaitEvent(testTimeout, "/", "c"); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []interface{}
+	for _, p := range conn.Packets() {
+		if p.Type == parser.EVENT {
+			events = append(events, p.Data.([]interface{})[0])
+		}
+	}
+	if len(events) != 2 || events[0] != "b" || events[1] != "c" {
+		t.Fatalf("got %v", events)
+	}
+}
+
+func TestSendOffline(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL)
+	if err := c.Emit("a"); err != socketio.ErrorNotConnected {
+		t.Fatalf("got %v, want ErrorNotConnected", err)
+	}
+	// never sent, not lost before the ack
+	if _, err := c.EmitWithAck("a").Result(); err != socketio.ErrorNotConnected {
+		t.Fatalf("got %v from EmitWithAck, want ErrorNotConnected", err)
+	}
+}
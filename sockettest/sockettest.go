@@ -0,0 +1,535 @@
+package sockettest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SavvasMohito/go-socket.io-client/parser"
+	"github.com/SavvasMohito/go-socket.io-client/protocol"
+	"github.com/SavvasMohito/go-socket.io-client/utils"
+	"github.com/SavvasMohito/go-socket.io-client/websocket"
+)
+
+const (
+	TestDefaultPingInterval = 25 * time.Second
+	TestDefaultPingTimeout  = 20 * time.Second
+)
+
+var (
+	ErrorTimeout    = errors.New("sockettest: timeout")
+	ErrorConnClosed = errors.New("sockettest: connection closed")
+)
+
+/*
+*
+Socket.IO server for the tests of code built on the client, it accepts
+websocket connections only, on any path ps: ws://127.0.0.1:port/socket.io/
+
+The handshake is scripted: the OPEN packet is sent with a new sid, and the
+namespaces are accepted unless rejected with Reject. Every packet sent by the
+client is recorded by its Conn.
+*/
+type Server struct {
+	// URL of the server, ps: http://127.0.0.1:port
+	URL string
+
+	// sent in the OPEN packet, read when a connection is opened
+	PingInterval time.Duration
+	PingTimeout  time.Duration
+
+	srv *httptest.Server
+	// connections opened so far, the first next ones are returned by Conn
+	conns   []*Conn
+	next    int
+	changed chan struct{}
+
+	rejected map[string]*rejection
+	counter  int
+	lock     sync.Mutex
+}
+
+type rejection struct {
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+/*
+*
+Starts a server, to be closed with Close
+*/
+func NewServer() *Server {
+	s := &Server{
+		PingInterval: TestDefaultPingInterval,
+		PingTimeout:  TestDefaultPingTimeout,
+		changed:      make(chan struct{}),
+		rejected:     make(map[string]*rejection),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.URL = s.srv.URL
+
+	return s
+}
+
+/*
+*
+Refuses the namespace with a CONNECT_ERROR carrying message and data
+*/
+func (s *Server) Reject(namespace string, message string, data interface{}) {
+	s.lock.Lock()
+	s.rejected[fmtNS(namespace)] = &rejection{Message: message, Data: data}
+	s.lock.Unlock()
+}
+
+/*
+*
+Accepts the namespace again after Reject
+*/
+func (s *Server) Accept(namespace string) {
+	s.lock.Lock()
+	delete(s.rejected, fmtNS(namespace))
+	s.lock.Unlock()
+}
+
+/*
+*
+Waits for the next connection of a client
+*/
+func (s *Server) Conn(timeout time.Duration) (*Conn, error) {
+	deadline := time.After(timeout)
+	for {
+		s.lock.Lock()
+		if s.next < len(s.conns) {
+			c := s.conns[s.next]
+			s.next++
+			s.lock.Unlock()
+			return c, nil
+		}
+		changed := s.changed
+		s.lock.Unlock()
+
+		select {
+		case <-changed:
+		case <-deadline:
+			return nil, ErrorTimeout
+		}
+	}
+}
+
+/*
+*
+Drops the connections and stops the server
+*/
+func (s *Server) Close() {
+	s.lock.Lock()
+	conns := s.conns
+	s.lock.Unlock()
+
+	for _, c := range conns {
+		c.Drop()
+	}
+	s.srv.Close()
+}
+
+func (s *Server) nextId() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.counter++
+	return "sockettest-" + strconv.Itoa(s.counter)
+}
+
+func (s *Server) rejection(namespace string) *rejection {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.rejected[namespace]
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	tr := websocket.GetDefaultWebsocketTransport()
+	if r.URL.Query().Get("EIO") == "3" {
+		tr.Protocol = protocol.Protocol3
+	}
+
+	ws, err := tr.HandleConnection(w, r)
+	if err != nil {
+		utils.Debug("[sockettest] upgrade:", err)
+		return
+	}
+
+	c := &Conn{
+		server:  s,
+		ws:      ws,
+		request: r,
+		sid:     s.nextId(),
+		acks:    make(map[int]chan []interface{}),
+		changed: make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+
+	open, _ := utils.Json.MarshalToString(map[string]interface{}{
+		"sid":          c.sid,
+		"upgrades":     []string{},
+		"pingInterval": s.PingInterval.Milliseconds(),
+		"pingTimeout":  s.PingTimeout.Milliseconds(),
+	})
+	if err := c.write(protocol.OpenMsg + open); err != nil {
+		ws.Close()
+		return
+	}
+
+	// in protocol v3 the default namespace is connected with the OPEN packet,
+	// in protocol v4 the server sends the pings
+	if tr.Protocol == protocol.Protocol3 {
+		c.write(protocol.CommonMsg + protocol.OpenMsg)
+	} else {
+		go c.ping(s.PingInterval)
+	}
+
+	s.lock.Lock()
+	s.conns = append(s.conns, c)
+	close(s.changed)
+	s.changed = make(chan struct{})
+	s.lock.Unlock()
+
+	c.read()
+}
+
+/*
+*
+Engine.IO connection of a client to the server
+*/
+type Conn struct {
+	server  *Server
+	ws      *websocket.Connection
+	request *http.Request
+	sid     string
+
+	writeLock sync.Mutex
+
+	// packets sent by the client, the taken ones were returned by WaitPacket
+	packets []*parser.Packet
+	taken   []bool
+	changed chan struct{}
+
+	acks  map[int]chan []interface{}
+	ackId int
+	lock  sync.Mutex
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+/*
+*
+Engine.IO session id sent in the OPEN packet
+*/
+func (c *Conn) Sid() string {
+	return c.sid
+}
+
+/*
+*
+Handshake request of the client, ps: to check its headers and query
+*/
+func (c *Conn) Request() *http.Request {
+	return c.request
+}
+
+/*
+*
+Sends an event to the client
+*/
+func (c *Conn) Emit(namespace string, event string, args ...interface{}) error {
+	return c.send(parser.Packet{
+		Type: parser.EVENT,
+		Nsp:  namespace,
+		Data: append([]interface{}{event}, args...),
+	})
+}
+
+/*
+*
+Sends an event to the client and waits for its ack
+*/
+func (c *Conn) EmitWithAck(namespace string, timeout time.Duration, event string, args ...interface{}) ([]interface{}, error) {
+	c.lock.Lock()
+	c.ackId++
+	id := c.ackId
+	waiter := make(chan []interface{}, 1)
+	c.acks[id] = waiter
+	c.lock.Unlock()
+
+	defer func() {
+		c.lock.Lock()
+		delete(c.acks, id)
+		c.lock.Unlock()
+	}()
+
+	err := c.send(parser.Packet{
+		Type:    parser.EVENT,
+		Nsp:     namespace,
+		Data:    append([]interface{}{event}, args...),
+		Id:      id,
+		NeedAck: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case res := <-waiter:
+		return res, nil
+	case <-c.closed:
+		return nil, ErrorConnClosed
+	case <-time.After(timeout):
+		return nil, ErrorTimeout
+	}
+}
+
+/*
+*
+Answers an event the client sent with an ack id
+*/
+func (c *Conn) Ack(namespace string, id int, args ...interface{}) error {
+	if args == nil {
+		args = []interface{}{}
+	}
+
+	return c.send(parser.Packet{
+		Type:    parser.ACK,
+		Nsp:     namespace,
+		Data:    args,
+		Id:      id,
+		NeedAck: true,
+	})
+}
+
+/*
+*
+Disconnects the namespace with a DISCONNECT, the client does not reconnect it
+*/
+func (c *Conn) Disconnect(namespace string) error {
+	return c.send(parser.Packet{Type: parser.DISCONNECT, Nsp: namespace})
+}
+
+/*
+*
+Closes the connection with an engine.io CLOSE packet
+*/
+func (c *Conn) Close() {
+	c.write(protocol.CloseMsg)
+	c.Drop()
+}
+
+/*
+*
+Drops the connection without a word, the way a network failure would
+*/
+func (c *Conn) Drop() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	c.ws.Close()
+}
+
+/*
+*
+Returns the packets sent by the client so far
+*/
+func (c *Conn) Packets() []parser.Packet {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	packets := make([]parser.Packet, 0, len(c.packets))
+	for _, p := range c.packets {
+		packets = append(packets, *p)
+	}
+	return packets
+}
+
+/*
+*
+Waits for a packet of the client matching match, which was not returned
+by a previous call, the packets already received are looked at first
+*/
+func (c *Conn) WaitPacket(timeout time.Duration, match func(p *parser.Packet) bool) (*parser.Packet, error) {
+	deadline := time.After(timeout)
+	for {
+		c.lock.Lock()
+		for i, p := range c.packets {
+			if !c.taken[i] && match(p) {
+				c.taken[i] = true
+				c.lock.Unlock()
+				return p, nil
+			}
+		}
+		changed := c.changed
+		c.lock.Unlock()
+
+		select {
+		case <-changed:
+		case <-c.closed:
+			return nil, ErrorConnClosed
+		case <-deadline:
+			return nil, ErrorTimeout
+		}
+	}
+}
+
+/*
+*
+Waits for an event of the client on the namespace, and returns its packet
+*/
+func (c *Conn) WaitEvent(timeout time.Duration, namespace string, event string) (*parser.Packet, error) {
+	return c.WaitPacket(timeout, func(p *parser.Packet) bool {
+		if p.Type != parser.EVENT && p.Type != parser.BINARY_EVENT {
+			return false
+		}
+		data, _ := p.Data.([]interface{})
+		return fmtNS(p.Nsp) == fmtNS(namespace) && len(data) > 0 && data[0] == event
+	})
+}
+
+/*
+*
+Waits for the namespace CONNECT of the client, and returns its packet
+*/
+func (c *Conn) WaitConnect(timeout time.Duration, namespace string) (*parser.Packet, error) {
+	return c.WaitPacket(timeout, func(p *parser.Packet) bool {
+		return p.Type == parser.CONNECT && fmtNS(p.Nsp) == fmtNS(namespace)
+	})
+}
+
+func (c *Conn) send(packet parser.Packet) error {
+	frames := parser.Encode(packet)
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if err := c.ws.WriteMessage(protocol.CommonMsg + string(frames[0])); err != nil {
+		return err
+	}
+	for _, attachment := range frames[1:] {
+		if err := c.ws.WriteMessage(attachment); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Conn) write(msg string) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	return c.ws.WriteMessage(msg)
+}
+
+func (c *Conn) ping(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if c.write(protocol.PingMsg) != nil {
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+func (c *Conn) read() {
+	defer c.Drop()
+
+	decoder := parser.NewDecoder(c.received)
+	for {
+		msg, err := c.ws.GetMessage()
+		if err != nil {
+			return
+		}
+		if msg == "" {
+			continue
+		}
+
+		switch string(msg[0]) {
+		case protocol.CloseMsg:
+			return
+		case protocol.PingMsg:
+			// in protocol v3 the client sends the pings
+			c.write(protocol.PongMsg + msg[1:])
+		case protocol.CommonMsg:
+			err = decoder.Add(msg[1:])
+		case protocol.BinaryMsg:
+			err = decoder.Add([]byte(msg[1:]))
+		}
+
+		if err != nil {
+			utils.Debug("[sockettest] bad packet:", err)
+		}
+	}
+}
+
+/*
+*
+Records a packet of the client, and answers its CONNECT and ACK
+*/
+func (c *Conn) received(packet *parser.Packet) {
+	// the numbers are checked as float64, as decoded by encoding/json
+	packet.Data = parser.Float64Numbers(packet.Data)
+
+	c.lock.Lock()
+	c.packets = append(c.packets, packet)
+	c.taken = append(c.taken, false)
+	close(c.changed)
+	c.changed = make(chan struct{})
+	c.lock.Unlock()
+
+	switch packet.Type {
+	case parser.CONNECT:
+		c.connect(packet)
+	case parser.ACK, parser.BINARY_ACK:
+		c.lock.Lock()
+		waiter, ok := c.acks[packet.Id]
+		c.lock.Unlock()
+
+		if ok {
+			data, _ := packet.Data.([]interface{})
+			waiter <- data
+		}
+	}
+}
+
+func (c *Conn) connect(packet *parser.Packet) {
+	if r := c.server.rejection(fmtNS(packet.Nsp)); r != nil {
+		data := interface{}(r)
+		// in protocol v3 the error is a string
+		if c.ws.GetProtocol() == protocol.Protocol3 {
+			data = r.Message
+		}
+
+		c.send(parser.Packet{Type: parser.CONNECT_ERROR, Nsp: packet.Nsp, Data: data})
+		return
+	}
+
+	var data interface{}
+	if c.ws.GetProtocol() != protocol.Protocol3 {
+		data = map[string]interface{}{"sid": c.server.nextId()}
+	}
+
+	c.send(parser.Packet{Type: parser.CONNECT, Nsp: packet.Nsp, Data: data})
+}
+
+func fmtNS(namespace string) string {
+	if namespace == "" {
+		return protocol.DefaultNsp
+	}
+
+	return namespace
+}
@@ -0,0 +1,114 @@
+package sockettest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+)
+
+const timeout = 2 * time.Second
+
+func TestServer(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	b := &socketio.ClientBuilder{}
+	c, err := b.Build(srv.URL, b.WithAuth(map[string]string{"token": "t"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	got := make(chan string, 1)
+	c.On("hello", func(ch *socketio.Channel, s string) string {
+		got <- s
+		return "hi " + s
+	})
+
+	if err := c.ConnectContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := srv.Conn(timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connect, err := conn.WaitConnect(timeout, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth, _ := connect.Data.(map[string]interface{}); auth["token"] != "t" {
+		t.Fatalf("got auth %v", connect.Data)
+	}
+
+	res, err := conn.EmitWithAck("/", timeout, "hello", "world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if <-got != "world" || len(res) != 1 || res[0] != "hi world" {
+		t.Fatalf("got ack %v", res)
+	}
+
+	if err := c.Emit("message", 1, "two"); err != nil {
+		t.Fatal(err)
+	}
+	event, err := conn.WaitEvent(timeout, "/", "message")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data := event.Data.([]interface{}); len(data) != 3 || data[1] != float64(1) || data[2] != "two" {
+		t.Fatalf("got event %v", event.Data)
+	}
+}
+
+func TestServerReject(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+	srv.Reject("/admin", "not authorized", map[string]interface{}{"code": 401})
+
+	b := &socketio.ClientBuilder{}
+	c, _ := b.Build(srv.URL+"/admin", b.WithIsAuthError(func(err *socketio.ConnectError) bool {
+		return false
+	}))
+	defer c.Close()
+
+	var connectErr *socketio.ConnectError
+	err := c.ConnectContext(context.Background())
+	if !errors.As(err, &connectErr) || connectErr.Message != "not authorized" {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestServerDrop(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	b := &socketio.ClientBuilder{}
+	c, _ := b.Build(srv.URL, b.WithReconnection(true), b.WithReconnectionDelay(10*time.Millisecond))
+	defer c.Close()
+
+	reconnected := make(chan struct{}, 1)
+	c.On(socketio.OnReconnect, func(ch *socketio.Channel) { reconnected <- struct{}{} })
+
+	if err := c.ConnectContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := srv.Conn(timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Drop()
+
+	select {
+	case <-reconnected:
+	case <-time.After(timeout):
+		t.Fatal("not reconnected")
+	}
+	if _, err := srv.Conn(timeout); err != nil {
+		t.Fatal(err)
+	}
+}
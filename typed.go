@@ -0,0 +1,137 @@
+package socketio
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	ErrorHandlerType = errors.New("handler argument can not be decoded from json")
+)
+
+/*
+*
+Passed to the handlers registered with On and Once of the package
+*/
+type Context struct {
+	Channel *Channel
+	Event   string
+	// arguments of the event as decoded by the parser
+	Args []interface{}
+	// answers the ack request of the server at most once. With On and Once it
+	// is answered automatically when the handlers return without calling it,
+	// the handlers registered with OnAck may call it later from any goroutine
+	Ack Ack
+}
+
+/*
+*
+Decode failure of the argument of a handler, reported to the OnError listeners
+*/
+type DecodeError struct {
+	Event string
+	Err   error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("socket.io: decode %q: %v", e.Event, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+/*
+*
+Adds a listener of event decoding its first argument into T.
+Decode failures and the errors returned by f are passed to the OnError listeners.
+*/
+func On[T any](c *Client, event string, f func(ctx Context, v T) error) error {
+	return addTypedListener(&c.handlers, event, f, false, false)
+}
+
+/*
+*
+Same as On, the listener is removed after its first call
+*/
+func Once[T any](c *Client, event string, f func(ctx Context, v T) error) error {
+	return addTypedListener(&c.handlers, event, f, true, false)
+}
+
+/*
+*
+Same as On, the listener answers the ack request itself with ctx.Ack,
+possibly after it returned, the empty answer is not sent for it
+*/
+func OnAck[T any](c *Client, event string, f func(ctx Context, v T) error) error {
+	return addTypedListener(&c.handlers, event, f, false, true)
+}
+
+func addTypedListener[T any](m *methods, event string, f func(ctx Context, v T) error, once, takesAck bool) error {
+	if err := checkDecodable(reflect.TypeOf((*T)(nil)).Elem()); err != nil {
+		return err
+	}
+
+	typed := func(c *Channel, event string, args []interface{}, ack Ack) error {
+		var v T
+		if len(args) > 0 {
+			if err := decodeAs(args[0], &v); err != nil {
+				return &DecodeError{Event: event, Err: err}
+			}
+		}
+
+		return f(Context{Channel: c, Event: event, Args: args, Ack: ack}, v)
+	}
+
+	m.addCaller(event, &caller{
+		typed:    typed,
+		id:       funcId(f),
+		once:     once,
+		takesAck: takesAck,
+	})
+	return nil
+}
+
+/*
+*
+Rejects the types json can not decode into, ps: chan and func
+*/
+func checkDecodable(t reflect.Type) error {
+	return checkType(t, make(map[reflect.Type]bool))
+}
+
+/*
+*
+Checks t unless already seen, the recursive types refer to themselves
+*/
+func checkType(t reflect.Type, seen map[reflect.Type]bool) error {
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return fmt.Errorf("%w: %s", ErrorHandlerType, t)
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return checkType(t.Elem(), seen)
+	case reflect.Map:
+		if err := checkType(t.Key(), seen); err != nil {
+			return err
+		}
+		return checkType(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() || field.Tag.Get("json") == "-" {
+				continue
+			}
+			if err := checkType(field.Type, seen); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package socketio_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	socketio "github.com/SavvasMohito/go-socket.io-client"
+	"github.com/SavvasMohito/go-socket.io-client/sockettest"
+)
+
+type tree struct {
+	Name string  `json:"name"`
+	Kids []*tree `json:"kids"`
+}
+
+type badTree struct {
+	Kids []*badTree
+	C    chan int
+}
+
+func TestTypedHandlers(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL)
+
+	type update struct {
+		Id    int     `json:"id"`
+		Price float64 `json:"price"`
+	}
+	got := make(chan update, 4)
+	errs := make(chan error, 4)
+	h := func(ctx socketio.Context, v update) error {
+		if ctx.Event != "update" {
+			t.Errorf("got event %q", ctx.Event)
+		}
+		got <- v
+		return nil
+	}
+	if err := socketio.On(c, "update", h); err != nil {
+		t.Fatal(err)
+	}
+	socketio.On(c, socketio.OnError, func(ctx socketio.Context, err error) error {
+		errs <- err
+		// not reported again
+		return errors.New("ignored")
+	})
+
+	connectTestClient(t, c)
+	conn, err := srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Emit("/", "update", map[string]interface{}{"id": 1, "price": 2.5})
+	select {
+	case v := <-got:
+		if v.Id != 1 || v.Price != 2.5 {
+			t.Fatalf("got %+v", v)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("no update")
+	}
+
+	conn.Emit("/", "update", "not an object")
+	var decodeErr *socketio.DecodeError
+	select {
+	case err := <-errs:
+		if !errors.As(err, &decodeErr) || decodeErr.Event != "update" {
+			t.Fatalf("got %v", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("no decode error")
+	}
+
+	c.Off("update", h)
+	if n := c.ListenerCount("update"); n != 0 {
+		t.Fatalf("got %d listeners", n)
+	}
+}
+
+func TestTypedHandlerTypes(t *testing.T) {
+	srv := sockettest.NewServer()
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL)
+
+	if err := socketio.On(c, "bad", func(ctx socketio.Context, v struct{ C chan int }) error { return nil }); !errors.Is(err, socketio.ErrorHandlerType) {
+		t.Fatalf("got %v, want ErrorHandlerType", err)
+	}
+	if err := socketio.On(c, "bad", func(ctx socketio.Context, v badTree) error { return nil }); !errors.Is(err, socketio.ErrorHandlerType) {
+		t.Fatalf("got %v, want ErrorHandlerType", err)
+	}
+
+	// recursive types are checked once
+	trees := make(chan tree, 1)
+	if err := socketio.On(c, "tree", func(ctx socketio.Context, v tree) error { trees <- v; return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	connectTestClient(t, c)
+	conn, err := srv.Conn(testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Emit("/", "tree", map[string]interface{}{"name": "root", "kids": []interface{}{map[string]interface{}{"name": "kid"}}})
+	select {
+	case v := <-trees:
+		if v.Name != "root" || len(v.Kids) != 1 || v.Kids[0].Name != "kid" {
+			t.Fatalf("got %+v", v)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("no tree")
+	}
+}
@@ -11,9 +11,9 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/SavvasMohito/go-socket.io-client/protocol"
+	"github.com/SavvasMohito/go-socket.io-client/utils"
 	"github.com/gorilla/websocket"
-	"github.com/mark0725/go-socket.io-client/protocol"
-	"github.com/mark0725/go-socket.io-client/utils"
 	"github.com/ugorji/go/codec"
 )
 
@@ -28,6 +28,9 @@ const (
 
 	maxRecordReadBytes  = 1024 * 1024 * 1024
 	maxRecordWriteBytes = 1024 * 1024 * 1024
+
+	// in protocol v3 binary messages start with the message type
+	binaryPrefixV3 = 4
 )
 
 const (
@@ -131,7 +134,14 @@ func (wsc *Connection) WriteMessage(message interface{}) error {
 	var data []byte
 
 	messageType := websocket.TextMessage
-	if reflect.TypeOf(message).Kind() == reflect.String {
+	if attachment, ok := message.([]byte); ok {
+		// binary attachment, in protocol v3 prefixed with the message type
+		messageType = websocket.BinaryMessage
+		data = attachment
+		if wsc.transport.Protocol == protocol.Protocol3 {
+			data = append([]byte{binaryPrefixV3}, attachment...)
+		}
+	} else if reflect.TypeOf(message).Kind() == reflect.String {
 		data = []byte(message.(string))
 	} else {
 		if wsc.transport.BinaryMessage {
@@ -168,6 +178,16 @@ func (wsc *Connection) decodeMessage(data []byte, messageType int) (string, erro
 		utils.Debug("[decodeMessage]", string(data))
 		return string(data), nil
 	}
+	// in text mode binary messages are the attachments of a packet
+	if !wsc.transport.BinaryMessage {
+		if wsc.transport.Protocol == protocol.Protocol3 && len(data) > 0 {
+			data = data[1:]
+		}
+
+		utils.Debug("[decodeMessage] attachment", len(data))
+		return protocol.BinaryMsg + string(data), nil
+	}
+
 	prefix := ""
 
 	if wsc.transport.Protocol == protocol.Protocol3 {
@@ -200,34 +220,8 @@ func (wsc *Connection) decodeMessage(data []byte, messageType int) (string, erro
 }
 
 func (wsc *Connection) encodeMessage(msg *protocol.MsgPack, messageType int) ([]byte, error) {
-	// {
-	//  "type": 3,
-	//  "nsp": "/admin",
-	//  "data": [],
-	//  "id": 456
-	// }
-	// is encoded to 3/admin,456[]
-
-	packet := ""
 	if messageType == websocket.TextMessage {
-		// Engine.IO Flag
-		prefix := protocol.CommonMsg
-		// Socket.IO Flag
-		event := strconv.Itoa(msg.Type)
-		ackId := strconv.Itoa(msg.Id)
-		data, _ := utils.Json.Marshal(&msg.Data)
-
-		packet = prefix + event
-		if msg.Nsp != "" && msg.Nsp != protocol.DefaultNsp {
-			packet = packet + msg.Nsp + ","
-		}
-
-		// sending ack res or sending ack req
-		if msg.Type == protocol.ACK || msg.Id >= 0 {
-			packet = packet + ackId + string(data)
-		} else {
-			packet = packet + string(data)
-		}
+		packet := protocol.EncodeTextMsg(msg)
 
 		utils.Debug("[encodeMessage]", packet)
 		return []byte(packet), nil